@@ -0,0 +1,86 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/chriswalker/gmi-utils/gemini"
+)
+
+// sample records the outcome of a single request.
+type sample struct {
+	// Status is zero if no response was received
+	status    int
+	latency   time.Duration
+	handshake time.Duration
+	err       error
+}
+
+// bench runs a load test against a set of URLs.
+type bench struct {
+	client      *gemini.Client
+	urls        []string
+	concurrency int
+	// Requests to make in total; if zero, requests are made
+	// until duration has elapsed
+	requests int
+	duration time.Duration
+}
+
+// run runs the load test, returning statistics for all requests made.
+func (b *bench) run() *stats {
+	var (
+		next     int64 = -1
+		deadline       = time.Now().Add(b.duration)
+		wg       sync.WaitGroup
+		samples  = make([][]sample, b.concurrency)
+	)
+
+	start := time.Now()
+	for w := 0; w < b.concurrency; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for {
+				i := atomic.AddInt64(&next, 1)
+				if b.requests > 0 && i >= int64(b.requests) {
+					return
+				}
+				if b.requests == 0 && time.Now().After(deadline) {
+					return
+				}
+				// URLs are requested in turn
+				url := b.urls[i%int64(len(b.urls))]
+				samples[w] = append(samples[w], b.request(url))
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	s := newStats(time.Since(start))
+	for _, ws := range samples {
+		for _, smp := range ws {
+			s.add(smp)
+		}
+	}
+
+	return s
+}
+
+// request makes a single request to the supplied URL.
+func (b *bench) request(url string) sample {
+	start := time.Now()
+	rsp, err := b.client.Get(url)
+	smp := sample{latency: time.Since(start), err: err}
+
+	// Failure statuses come back with both a response and an
+	// error; they count as responses rather than errors here
+	if rsp != nil {
+		smp.status = rsp.StatusCode
+		smp.handshake = rsp.HandshakeDuration
+		smp.err = nil
+	}
+
+	return smp
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"crypto/tls"
+	"testing"
+	"time"
+
+	"github.com/chriswalker/gmi-utils/gemini"
+	"github.com/chriswalker/gmi-utils/gemini/geminitest"
+)
+
+func TestBenchRun(t *testing.T) {
+	svr, err := geminitest.NewServer(map[string]string{
+		"/":          "20 text/gemini\r\n# Capsule\n",
+		"/image.png": "20 image/png\r\n\x89PNG\r\n\x1a\n",
+		"/notes.txt": "20 text/plain\r\nNotes\n",
+		"/busy":      "40 server busy\r\n",
+	})
+	if err != nil {
+		t.Fatal("unable to start test server:", err)
+	}
+	defer svr.Close()
+
+	testCases := map[string]struct {
+		paths    []string
+		statuses map[int]int
+	}{
+		"gemtext": {
+			paths:    []string{"/"},
+			statuses: map[int]int{gemini.StatusSuccess: 4},
+		},
+		"non-gemtext": {
+			paths:    []string{"/image.png", "/notes.txt"},
+			statuses: map[int]int{gemini.StatusSuccess: 4},
+		},
+		"failures": {
+			paths:    []string{"/", "/busy"},
+			statuses: map[int]int{gemini.StatusSuccess: 2, gemini.StatusTemporaryFailure: 2},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var urls []string
+			for _, path := range tc.paths {
+				urls = append(urls, svr.URL+path)
+			}
+			b := &bench{
+				client: gemini.NewClient(
+					gemini.Timeout(5*time.Second),
+					gemini.Config(&tls.Config{InsecureSkipVerify: true}),
+					gemini.FollowRedirects(false),
+				),
+				urls:        urls,
+				concurrency: 2,
+				requests:    4,
+			}
+			s := b.run()
+
+			if s.errors != 0 {
+				t.Errorf("got %d errors, want none: %v", s.errors, s.errorMsgs)
+			}
+			if len(s.statuses) != len(tc.statuses) {
+				t.Errorf("got statuses %v, want %v", s.statuses, tc.statuses)
+			}
+			for status, count := range tc.statuses {
+				if s.statuses[status] != count {
+					t.Errorf("got %d responses with status %d, want %d", s.statuses[status], status, count)
+				}
+			}
+			if len(s.handshakes) != 4 {
+				t.Errorf("got %d handshake samples, want 4", len(s.handshakes))
+			}
+		})
+	}
+}
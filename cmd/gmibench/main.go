@@ -0,0 +1,112 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/chriswalker/gmi-utils/cli"
+	"github.com/chriswalker/gmi-utils/gemini"
+)
+
+const (
+	name  = "gmibench"
+	desc  = "gmibench - load tests Gemini servers"
+	usage = `  gmibench [flags...] <url> [<url>...]
+
+  # 20 workers making 5000 requests in total against a local server
+  gmibench -c 20 -n 5000 gemini://localhost/
+
+  # 10 workers hitting two pages in turn for 30 seconds
+  gmibench -d 30s gemini://localhost/ gemini://localhost/about.gmi
+
+Redirects are not followed, so each request measures a single
+response.`
+)
+
+var (
+	help        bool
+	concurrency int
+	requests    int
+	duration    time.Duration
+	timeout     time.Duration
+)
+
+func main() {
+	flag.BoolVar(&help, "help", false, "Show help for gmibench")
+	flag.BoolVar(&help, "h", false, "Show help for gmibench")
+	flag.IntVar(&concurrency, "concurrency", 10, "Number of workers making requests concurrently")
+	flag.IntVar(&concurrency, "c", 10, "Number of workers making requests concurrently")
+	flag.IntVar(&requests, "requests", 0, "Total number of requests to make; overrides duration")
+	flag.IntVar(&requests, "n", 0, "Total number of requests to make; overrides duration")
+	flag.DurationVar(&duration, "duration", 10*time.Second, "How long to make requests for")
+	flag.DurationVar(&duration, "d", 10*time.Second, "How long to make requests for")
+	flag.DurationVar(&timeout, "timeout", 10*time.Second, "Connection timeout for each request")
+
+	flag.Usage = cli.Usage(cli.UsageOptions{
+		Description: desc,
+		Usage:       usage,
+	}, os.Stdout)
+	flag.Parse()
+
+	if help {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	urls, err := getURLs()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
+		os.Exit(1)
+	}
+	if concurrency < 1 {
+		fmt.Fprintf(os.Stderr, "%s: concurrency must be at least 1\n", name)
+		os.Exit(1)
+	}
+
+	b := &bench{
+		client: gemini.NewClient(
+			gemini.Timeout(timeout),
+			gemini.Config(&tls.Config{InsecureSkipVerify: true}),
+			gemini.FollowRedirects(false),
+		),
+		urls:        urls,
+		concurrency: concurrency,
+		requests:    requests,
+		duration:    duration,
+	}
+
+	if requests > 0 {
+		fmt.Printf("Making %d requests to %d URL(s) with %d workers...\n\n", requests, len(urls), concurrency)
+	} else {
+		fmt.Printf("Making requests to %d URL(s) with %d workers for %s...\n\n", len(urls), concurrency, duration)
+	}
+
+	s := b.run()
+	s.report(os.Stdout)
+
+	if s.errors > 0 {
+		os.Exit(1)
+	}
+}
+
+// getURLs gets the URLs to request from the command line args,
+// defaulting their scheme to gemini://.
+func getURLs() ([]string, error) {
+	urls := flag.Args()
+	if len(urls) == 0 {
+		return nil, errors.New("missing Gemini URL")
+	}
+
+	for i, url := range urls {
+		if !strings.Contains(url, "://") {
+			urls[i] = fmt.Sprintf("%s://%s", gemini.Scheme, url)
+		}
+	}
+
+	return urls, nil
+}
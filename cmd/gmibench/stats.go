@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chriswalker/gmi-utils/gemini"
+)
+
+// stats aggregates the samples recorded during a load test.
+type stats struct {
+	elapsed    time.Duration
+	latencies  []time.Duration
+	handshakes []time.Duration
+	statuses   map[int]int
+	errors     int
+	errorMsgs  map[string]int
+}
+
+func newStats(elapsed time.Duration) *stats {
+	return &stats{
+		elapsed:   elapsed,
+		statuses:  make(map[int]int),
+		errorMsgs: make(map[string]int),
+	}
+}
+
+// add records a single sample.
+func (s *stats) add(smp sample) {
+	if smp.err != nil {
+		s.errors++
+		s.errorMsgs[smp.err.Error()]++
+		return
+	}
+
+	s.statuses[smp.status]++
+	s.latencies = append(s.latencies, smp.latency)
+	s.handshakes = append(s.handshakes, smp.handshake)
+}
+
+// report writes a summary of the load test to the supplied writer.
+func (s *stats) report(w io.Writer) {
+	total := len(s.latencies) + s.errors
+
+	fmt.Fprintf(w, "Requests:      %d (%d responses, %d errors)\n", total, len(s.latencies), s.errors)
+	fmt.Fprintf(w, "Elapsed:       %s\n", s.elapsed.Round(time.Millisecond))
+	fmt.Fprintf(w, "Throughput:    %.1f req/s\n", float64(total)/s.elapsed.Seconds())
+
+	if len(s.latencies) > 0 {
+		fmt.Fprintf(w, "\nLatency:\n%s", summarise(s.latencies))
+
+		// Handshake cost relative to the whole request
+		fmt.Fprintf(w, "\nTLS handshake: (%.1f%% of mean latency)\n%s",
+			100*float64(mean(s.handshakes))/float64(mean(s.latencies)),
+			summarise(s.handshakes))
+
+		fmt.Fprintln(w, "\nStatus codes:")
+		codes := make([]int, 0, len(s.statuses))
+		for code := range s.statuses {
+			codes = append(codes, code)
+		}
+		sort.Ints(codes)
+		for _, code := range codes {
+			fmt.Fprintf(w, "  %-34s %d\n", gemini.Status(code), s.statuses[code])
+		}
+	}
+
+	if s.errors > 0 {
+		fmt.Fprintln(w, "\nErrors:")
+		msgs := make([]string, 0, len(s.errorMsgs))
+		for msg := range s.errorMsgs {
+			msgs = append(msgs, msg)
+		}
+		sort.Strings(msgs)
+		for _, msg := range msgs {
+			fmt.Fprintf(w, "  %d x %s\n", s.errorMsgs[msg], msg)
+		}
+	}
+}
+
+// summarise formats the min, mean, max and percentiles of the supplied
+// durations as an indented table.
+func summarise(durations []time.Duration) string {
+	sorted := make([]time.Duration, len(durations))
+	copy(sorted, durations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var b strings.Builder
+	row := func(label string, d time.Duration) {
+		fmt.Fprintf(&b, "  %-6s %s\n", label, d.Round(time.Microsecond))
+	}
+	row("min", sorted[0])
+	row("mean", mean(sorted))
+	row("p50", percentile(sorted, 50))
+	row("p90", percentile(sorted, 90))
+	row("p99", percentile(sorted, 99))
+	row("max", sorted[len(sorted)-1])
+
+	return b.String()
+}
+
+// percentile returns the pth percentile of the supplied sorted
+// durations, using the nearest-rank method.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func mean(durations []time.Duration) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+
+	var total time.Duration
+	for _, d := range durations {
+		total += d
+	}
+	return total / time.Duration(len(durations))
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+
+	"github.com/chriswalker/gmi-utils/cli"
+	"github.com/chriswalker/gmi-utils/gemtext"
+)
+
+const (
+	name  = "gmiconv"
+	desc  = "gmiconv - converts documents to and from gemtext"
+	usage = `  gmiconv -from markdown -f README.md > README.gmi
+
+  # Mirror a saved web article
+  gmiconv -from html -f article.html -base https://some.site/posts/ > article.gmi
+
+  # Pipe in gemtext via stdin
+  gmiget gemini://some-url/ | gmiconv -to markdown`
+)
+
+var (
+	help      bool
+	inputFile string
+	from      string
+	to        string
+	base      string
+)
+
+func main() {
+	flag.BoolVar(&help, "help", false, "Show help for gmiconv")
+	flag.BoolVar(&help, "h", false, "Show help for gmiconv")
+	flag.StringVar(&inputFile, "file", "", "File to convert")
+	flag.StringVar(&inputFile, "f", "", "File to convert")
+	flag.StringVar(&from, "from", "gemtext", "Input format: gemtext, markdown or html")
+	flag.StringVar(&to, "to", "gemtext", "Output format: gemtext or markdown")
+	flag.StringVar(&base, "base", "", "URL of an HTML document, to resolve relative links against")
+
+	flag.Usage = cli.Usage(cli.UsageOptions{
+		Description: desc,
+		Usage:       usage,
+	}, os.Stdout)
+	flag.Parse()
+
+	if help {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	var input io.Reader
+
+	// Require either a file, or something piped in on stdin
+	if inputFile != "" {
+		f, err := os.Open(inputFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		input = f
+	} else {
+		f, err := os.Stdin.Stat()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
+			os.Exit(1)
+		}
+
+		if f.Mode()&os.ModeNamedPipe == 0 {
+			fmt.Println("nothing passed into stdin - exiting.")
+			os.Exit(1)
+		}
+
+		input = os.Stdin
+	}
+
+	if err := convert(input, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
+		os.Exit(1)
+	}
+}
+
+// convert reads a document in the -from format from r, and writes it
+// to w in the -to format.
+func convert(r io.Reader, w io.Writer) error {
+	var doc *gemtext.Document
+	var err error
+
+	switch from {
+	case "gemtext":
+		doc, err = gemtext.Parse(r)
+	case "markdown":
+		doc, err = gemtext.ParseMarkdown(r)
+	case "html":
+		var opts []gemtext.Option
+		if base != "" {
+			baseURL, err := url.Parse(base)
+			if err != nil {
+				return err
+			}
+			opts = append(opts, gemtext.Base(baseURL))
+		}
+		doc, err = gemtext.ParseHTML(r, opts...)
+	default:
+		return fmt.Errorf("unknown input format '%s'", from)
+	}
+	if err != nil {
+		return err
+	}
+
+	switch to {
+	case "gemtext":
+		_, err = doc.WriteTo(w)
+	case "markdown":
+		err = gemtext.WriteMarkdown(w, doc)
+	default:
+		err = fmt.Errorf("unknown output format '%s'", to)
+	}
+
+	return err
+}
@@ -4,6 +4,7 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
 
 	"github.com/chriswalker/gmi-utils/cli"
@@ -17,7 +18,16 @@ const (
 	usage = `  gmifmt -f <file>,
 
   # Pipe in gemtext via stdin
-  gmiget gemini://some-url/ | gmifmt [flags...]`
+  gmiget gemini://some-url/ | gmifmt [flags...]
+
+  # Preview the available themes
+  gmifmt -list-themes
+
+  # Convert gemtext to a web page
+  gmifmt -f index.gmi -to html -page > index.html
+
+  # Convert gemtext to a man page
+  gmifmt -f gmifmt.gmi -to man -section 1 > gmifmt.1`
 )
 
 var (
@@ -25,8 +35,28 @@ var (
 	margin     int
 	inputFile  string
 	configFile string
+	altText    bool
+	overflow   string
+	hyphenFile string
+	maxWidth   int
+	justify    bool
+	number     bool
+	refs       string
+	base       string
+	external   bool
+	to         string
+	page       bool
+	cssFile    string
+	section    string
+	colour     string
+	themeName  string
+	list       bool
+	highlight  bool
 )
 
+// textWidth is the width plain text is wrapped to, unless set with -width.
+const textWidth = 72
+
 func main() {
 	flag.BoolVar(&help, "help", false, "Show help for gmifmt")
 	flag.BoolVar(&help, "h", false, "Show help for gmifmt")
@@ -36,6 +66,24 @@ func main() {
 	flag.StringVar(&inputFile, "f", "", "Gemtext file to format")
 	flag.StringVar(&configFile, "config", "", "Path to gmifmt configuration file")
 	flag.StringVar(&configFile, "c", "", "Path to gmifmt configuration file")
+	flag.IntVar(&maxWidth, "width", 0, "Maximum width of formatted text, centred in wider terminals")
+	flag.IntVar(&maxWidth, "w", 0, "Maximum width of formatted text, centred in wider terminals")
+	flag.BoolVar(&justify, "justify", false, "Fully justify text, rather than leaving it ragged-right")
+	flag.BoolVar(&altText, "alt", false, "Show alt text of preformatted blocks as a caption")
+	flag.StringVar(&overflow, "overflow", "none", "How to handle words too long for a line: none, break, url or ellipsis")
+	flag.StringVar(&hyphenFile, "hyphenate", "", "Path to a hyphenation dictionary to hyphenate words with")
+	flag.BoolVar(&number, "number", false, "Number links rather than showing their URLs inline")
+	flag.StringVar(&refs, "refs", "", "Where to list numbered link URLs: none, end or section (default none, or end for text)")
+	flag.StringVar(&base, "base", "", "URL of the page, to resolve relative links against")
+	flag.BoolVar(&external, "external", false, "Mark links to other capsules than -base")
+	flag.StringVar(&to, "to", "terminal", "Output format: terminal, text, man or html")
+	flag.BoolVar(&page, "page", false, "Output HTML as a complete page, with a stylesheet")
+	flag.StringVar(&cssFile, "css", "", "Path to a stylesheet to use in HTML pages in place of the default")
+	flag.StringVar(&section, "section", "7", "Manual section of man pages")
+	flag.StringVar(&colour, "color", "auto", "When to colour terminal output: auto, always, never, 16, 256 or truecolor")
+	flag.StringVar(&themeName, "theme", "", "Theme to style terminal output with, built-in or from the themes configuration directory; overrides styles in the configuration file")
+	flag.BoolVar(&list, "list-themes", false, "List the available themes, previewing each")
+	flag.BoolVar(&highlight, "highlight", false, "Syntax highlight preformatted blocks whose alt text names a known language")
 
 	flag.Usage = cli.Usage(cli.UsageOptions{
 		Description: desc,
@@ -48,6 +96,19 @@ func main() {
 		os.Exit(1)
 	}
 
+	if list {
+		depth, err := colourDepth(colour)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if err := listThemes(os.Stdout, terminal.GetWidth(), margin, depth); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	var input io.Reader
 
 	// Require either a file, or something piped in on stdin
@@ -73,17 +134,132 @@ func main() {
 		input = os.Stdin
 	}
 
-	config, err := config.Load(configFile)
+	conf, err := config.Load(configFile)
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
-	if config != nil {
-		gemtext.Configure(*config)
+
+	wordOverflow, err := gemtext.ParseOverflow(overflow)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	opts := []gemtext.Option{
+		gemtext.AltText(altText),
+		gemtext.WordOverflow(wordOverflow),
+		gemtext.MaxWidth(maxWidth),
+		gemtext.Justify(justify),
+		gemtext.NumberLinks(number),
+		gemtext.Highlight(highlight),
+	}
+	theme, err := loadTheme(themeName, conf)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
 	}
+	opts = append(opts, gemtext.Colours(theme))
 
-	fmt.Println()
+	// Leave each renderer's default placement unless one is given
+	if refs != "" {
+		references, err := gemtext.ParseReferences(refs)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		opts = append(opts, gemtext.LinkReferences(references))
+	}
 
-	width := terminal.GetWidth()
-	gemtext.Output(width, margin, input, os.Stdout)
+	if base != "" {
+		baseURL, err := url.Parse(base)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		opts = append(opts, gemtext.Base(baseURL), gemtext.MarkExternal(external))
+	}
+
+	if hyphenFile != "" {
+		f, err := os.Open(hyphenFile)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		hyphenation, err := gemtext.LoadHyphenation(f)
+		f.Close()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		opts = append(opts, gemtext.Hyphenate(hyphenation))
+	}
+
+	var renderer gemtext.Renderer
+	switch to {
+	case "terminal":
+		depth, err := colourDepth(colour)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		opts = append(opts, gemtext.ColourDepth(depth))
+
+		fmt.Println()
+
+		renderer = gemtext.NewTerminalRenderer(os.Stdout, terminal.GetWidth(), margin, opts...)
+	case "text":
+		width := textWidth
+		if maxWidth > 0 {
+			width = maxWidth
+		}
+		renderer = gemtext.NewTextRenderer(os.Stdout, width, opts...)
+	case "man":
+		opts = append(opts, gemtext.ManSection(section))
+		renderer = gemtext.NewManRenderer(os.Stdout, opts...)
+	case "html":
+		opts = append(opts, gemtext.FullPage(page))
+		if cssFile != "" {
+			css, err := os.ReadFile(cssFile)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			opts = append(opts, gemtext.Stylesheet(string(css)))
+		}
+		renderer = gemtext.NewHTMLRenderer(os.Stdout, opts...)
+	default:
+		fmt.Printf("unknown output format '%s'\n", to)
+		os.Exit(1)
+	}
+
+	if err := gemtext.Render(input, renderer); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// colourDepth returns the colour depth to output in for the supplied
+// -color flag value. Both auto and always detect the terminal's colour
+// depth, but auto falls back to no colour when output isn't to a
+// terminal or NO_COLOR is set.
+func colourDepth(mode string) (terminal.ColourDepth, error) {
+	switch mode {
+	case "auto":
+		return terminal.GetColourDepth(os.Stdout), nil
+	case "always":
+		if depth := terminal.DetectColourDepth(); depth > terminal.NoColour {
+			return depth, nil
+		}
+		return terminal.Colour16, nil
+	case "never":
+		return terminal.NoColour, nil
+	case "16":
+		return terminal.Colour16, nil
+	case "256":
+		return terminal.Colour256, nil
+	case "truecolor":
+		return terminal.TrueColour, nil
+	default:
+		return terminal.NoColour, fmt.Errorf("unknown colour mode '%s'", mode)
+	}
 }
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/chriswalker/gmi-utils/config"
+	"github.com/chriswalker/gmi-utils/gemtext"
+	"github.com/chriswalker/gmi-utils/terminal"
+)
+
+// sampleDocument is the gemtext themes are previewed on.
+const sampleDocument = "# Heading\n" +
+	"## Subheading\n" +
+	"### Minor heading\n" +
+	"Text, with a link below to more of it.\n" +
+	"=> gemini://some.capsule/more.gmi More text\n" +
+	"* A list item\n" +
+	"> A quote\n" +
+	"```\n" +
+	"Preformatted text\n" +
+	"```\n" +
+	"```go\n" +
+	"// Highlighted with -highlight\n" +
+	"fmt.Println(\"Hello\", 42)\n" +
+	"```\n"
+
+// loadTheme returns the named theme, or if there's no name the theme
+// named by the configuration's theme key, if any. See mergeStyles for
+// how the configuration's styles combine with the theme's.
+func loadTheme(name string, conf *config.Config) (gemtext.Theme, error) {
+	var styles config.Config
+	if conf != nil {
+		styles = *conf
+	}
+
+	explicit := name != ""
+	if !explicit {
+		name = styles["theme"]
+	}
+	theme := make(config.Config)
+	if name != "" {
+		var err error
+		if theme, err = config.LoadTheme(name); err != nil {
+			return gemtext.Theme{}, err
+		}
+	}
+
+	return gemtext.ThemeFromConfig(mergeStyles(theme, styles, explicit))
+}
+
+// mergeStyles combines the styles of a theme with those set in the
+// configuration. A theme given explicitly with -theme wins over the
+// configuration, whose styles only fill in those the theme leaves
+// unset; otherwise the configuration's styles are layered over the
+// theme's, adjusting the theme it names.
+func mergeStyles(theme, conf config.Config, explicit bool) config.Config {
+	layers := []config.Config{theme, conf}
+	if explicit {
+		layers = []config.Config{conf, theme}
+	}
+
+	styles := make(config.Config)
+	for _, layer := range layers {
+		for key, val := range layer {
+			styles[key] = val
+		}
+	}
+
+	return styles
+}
+
+// listThemes writes the name of each available theme to w, followed by
+// a preview of the theme on a sample document.
+func listThemes(w io.Writer, width, margin int, depth terminal.ColourDepth) error {
+	names, err := config.ThemeNames()
+	if err != nil {
+		return err
+	}
+
+	for i, name := range names {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "%s%s\n\n", strings.Repeat(" ", margin), name)
+
+		theme, err := loadTheme(name, nil)
+		if err != nil {
+			fmt.Fprintf(w, "%s%s\n", strings.Repeat(" ", margin), err)
+			continue
+		}
+		gemtext.Output(width, margin, strings.NewReader(sampleDocument), w,
+			gemtext.Colours(theme), gemtext.ColourDepth(depth), gemtext.Highlight(highlight))
+	}
+
+	return nil
+}
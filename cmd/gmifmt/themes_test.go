@@ -0,0 +1,41 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/chriswalker/gmi-utils/config"
+)
+
+func TestMergeStyles(t *testing.T) {
+	theme := config.Config{"header": "#88c0d0 bold", "link": "#5e81ac"}
+
+	testCases := map[string]struct {
+		conf     config.Config
+		explicit bool
+		expected config.Config
+	}{
+		"no configuration": {
+			explicit: true,
+			expected: config.Config{"header": "#88c0d0 bold", "link": "#5e81ac"},
+		},
+		"explicit theme wins": {
+			conf:     config.Config{"header": "#ff0000", "quoted": "italic"},
+			explicit: true,
+			expected: config.Config{"header": "#88c0d0 bold", "link": "#5e81ac", "quoted": "italic"},
+		},
+		"configured theme adjusted": {
+			conf:     config.Config{"theme": "nord", "header": "#ff0000", "quoted": "italic"},
+			expected: config.Config{"theme": "nord", "header": "#ff0000", "link": "#5e81ac", "quoted": "italic"},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			got := mergeStyles(theme, tc.conf, tc.explicit)
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("got styles %v, want %v", got, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/chriswalker/gmi-utils/gemini"
+	"github.com/chriswalker/gmi-utils/gemtext"
+)
+
+// result holds the outcome of fetching a single URL.
+type result struct {
+	url  string
+	resp *gemini.Response
+	err  error
+}
+
+// fetchAll fetches the supplied URLs using a pool of workers, no more
+// than parallel at a time. If link is non-zero, the link with that
+// number on each page is fetched in its place. Results are sent on the returned channel in
+// the order the URLs were supplied, regardless of the order in which
+// they complete; the channel is closed once all results are sent.
+func fetchAll(client *gemini.Client, urls []string, parallel, link int) <-chan result {
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	// Each URL gets its own buffered channel, so workers never block
+	// and results can be drained in order
+	pending := make([]chan result, len(urls))
+	for i := range pending {
+		pending[i] = make(chan result, 1)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				pending[j] <- get(client, urls[j], link)
+			}
+		}()
+	}
+
+	go func() {
+		for i := range urls {
+			jobs <- i
+		}
+		close(jobs)
+		wg.Wait()
+	}()
+
+	results := make(chan result)
+	go func() {
+		for _, p := range pending {
+			results <- <-p
+		}
+		close(results)
+	}()
+
+	return results
+}
+
+// get fetches the supplied URL. If link is non-zero, it instead
+// fetches the URL of the page's link with that number, counting links
+// in document order from 1 as gmifmt -number does.
+func get(client *gemini.Client, rawURL string, link int) result {
+	resp, err := client.Get(rawURL)
+	if err != nil || link == 0 {
+		return result{url: rawURL, resp: resp, err: err}
+	}
+
+	target, err := nthLink(resp, link)
+	if err != nil {
+		return result{url: rawURL, err: err}
+	}
+	resp, err = client.Get(target)
+
+	return result{url: target, resp: resp, err: err}
+}
+
+// parseLink parses the number of a link given to -link. Links are
+// numbered from 1.
+func parseLink(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid link number '%s'", s)
+	}
+	if n < 1 {
+		return 0, fmt.Errorf("invalid link number %d, links are numbered from 1", n)
+	}
+
+	return n, nil
+}
+
+// nthLink returns the absolute URL of the nth link in the supplied
+// gemtext response.
+func nthLink(resp *gemini.Response, n int) (string, error) {
+	mediaType, _, err := resp.MediaType()
+	if err != nil {
+		return "", fmt.Errorf("could not find links: %w", err)
+	}
+	if mediaType != gemini.MIMEType {
+		return "", fmt.Errorf("could not find links: page is %s, not gemtext", mediaType)
+	}
+
+	doc, err := gemtext.Parse(bytes.NewReader(resp.Body))
+	if err != nil {
+		return "", fmt.Errorf("could not parse page: %w", err)
+	}
+
+	count := 0
+	for _, line := range doc.Lines {
+		link, ok := line.(gemtext.Link)
+		if !ok {
+			continue
+		}
+		count++
+		if count < n {
+			continue
+		}
+
+		ref, err := url.Parse(link.URL)
+		if err != nil {
+			return "", fmt.Errorf("could not parse link %d: %w", n, err)
+		}
+		target := resp.URL.ResolveReference(ref)
+		if target.Scheme != gemini.Scheme {
+			return "", fmt.Errorf("link %d is not a Gemini URL: %s", n, target)
+		}
+		return target.String(), nil
+	}
+
+	return "", fmt.Errorf("page has %d links, no link %d", count, n)
+}
+
+// outputPath builds the path of the file a fetched URL is written to
+// within dir. The file name is derived from the URL's host and path,
+// e.g. gemini://some.url/a/b.gmi becomes some.url_a_b.gmi. As different
+// URLs can map to the same name, a name already in use is given a
+// numeric suffix before its extension; the path returned is added to
+// used.
+func outputPath(dir, rawURL string, used map[string]bool) string {
+	if !strings.Contains(rawURL, "://") {
+		rawURL = gemini.Scheme + "://" + rawURL
+	}
+
+	name, ext := rawURL, ""
+	if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+		name = u.Host + u.Path
+		if u.RawQuery != "" {
+			name += "_" + u.RawQuery
+		} else {
+			ext = path.Ext(u.Path)
+		}
+	}
+	name = strings.TrimSuffix(name, "/")
+
+	name = strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '?', '*', '"', '<', '>', '|':
+			return '_'
+		}
+		return r
+	}, name)
+	ext = name[len(name)-len(ext):]
+	if strings.HasSuffix(rawURL, "/") {
+		name, ext = name+"_index.gmi", ".gmi"
+	}
+
+	p := filepath.Join(dir, name)
+	for i := 2; used[p]; i++ {
+		p = filepath.Join(dir, fmt.Sprintf("%s_%d%s", strings.TrimSuffix(name, ext), i, ext))
+	}
+	used[p] = true
+
+	return p
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chriswalker/gmi-utils/gemini/geminitest"
+)
+
+func TestParseLink(t *testing.T) {
+	testCases := map[string]struct {
+		input    string
+		expected int
+		errMsg   string
+	}{
+		"first link": {
+			input:    "1",
+			expected: 1,
+		},
+		"later link": {
+			input:    "12",
+			expected: 12,
+		},
+		"zero": {
+			input:  "0",
+			errMsg: "invalid link number 0",
+		},
+		"negative": {
+			input:  "-3",
+			errMsg: "invalid link number -3",
+		},
+		"not a number": {
+			input:  "third",
+			errMsg: "invalid link number 'third'",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			n, err := parseLink(tc.input)
+
+			if tc.errMsg != "" {
+				if err == nil {
+					t.Fatalf("expected error '%s', got nil", tc.errMsg)
+				}
+				if !strings.Contains(err.Error(), tc.errMsg) {
+					t.Errorf("got error '%s', want '%s'", err, tc.errMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+			if n != tc.expected {
+				t.Errorf("got link %d, want %d", n, tc.expected)
+			}
+		})
+	}
+}
+
+func TestGetLink(t *testing.T) {
+	svr, err := geminitest.NewServer(map[string]string{
+		"/":      "20 text/gemini\r\n# Index\n=> /one One\nText\n=> two Two\n",
+		"/one":   "20 text/gemini\r\n# One\n",
+		"/two":   "20 text/gemini\r\n# Two\n",
+		"/a.png": "20 image/png\r\n\x89PNG\r\n",
+	})
+	if err != nil {
+		t.Fatal("unable to start test server:", err)
+	}
+	defer svr.Close()
+
+	testCases := map[string]struct {
+		path   string
+		link   int
+		url    string
+		errMsg string
+	}{
+		"first link": {
+			path: "/",
+			link: 1,
+			url:  svr.URL + "/one",
+		},
+		"second link": {
+			path: "/",
+			link: 2,
+			url:  svr.URL + "/two",
+		},
+		"missing link": {
+			path:   "/",
+			link:   3,
+			errMsg: "page has 2 links, no link 3",
+		},
+		"not gemtext": {
+			path:   "/a.png",
+			link:   1,
+			errMsg: "page is image/png, not gemtext",
+		},
+	}
+
+	client := newTestClient()
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			res := get(client, svr.URL+tc.path, tc.link)
+
+			if tc.errMsg != "" {
+				if res.err == nil {
+					t.Fatalf("expected error '%s', got nil", tc.errMsg)
+				}
+				if !strings.Contains(res.err.Error(), tc.errMsg) {
+					t.Errorf("got error '%s', want '%s'", res.err, tc.errMsg)
+				}
+				return
+			}
+			if res.err != nil {
+				t.Fatalf("unexpected error: %q", res.err)
+			}
+			if res.url != tc.url {
+				t.Errorf("got URL '%s', want '%s'", res.url, tc.url)
+			}
+		})
+	}
+}
+
+func TestFetchAll(t *testing.T) {
+	svr, err := geminitest.NewServer(map[string]string{
+		"/":    "20 text/gemini\r\n=> /one One\n=> /gone Gone\n",
+		"/one": "20 text/gemini\r\n# One\n",
+		"/two": "20 text/gemini\r\n# Two\n",
+	})
+	if err != nil {
+		t.Fatal("unable to start test server:", err)
+	}
+	defer svr.Close()
+
+	type want struct {
+		url    string
+		status int
+		errMsg string
+	}
+	testCases := map[string]struct {
+		paths    []string
+		parallel int
+		link     int
+		expected []want
+	}{
+		"in order": {
+			paths:    []string{"/one", "/two", "/one"},
+			parallel: 3,
+			expected: []want{
+				{url: "/one", status: 20},
+				{url: "/two", status: 20},
+				{url: "/one", status: 20},
+			},
+		},
+		"no parallelism": {
+			paths:    []string{"/two", "/one"},
+			parallel: 0,
+			expected: []want{
+				{url: "/two", status: 20},
+				{url: "/one", status: 20},
+			},
+		},
+		"failures": {
+			paths:    []string{"/missing", "/one"},
+			parallel: 2,
+			expected: []want{
+				{url: "/missing", status: 51, errMsg: "not found"},
+				{url: "/one", status: 20},
+			},
+		},
+		"links": {
+			paths:    []string{"/", "/one"},
+			parallel: 2,
+			link:     2,
+			expected: []want{
+				{url: "/gone", status: 51, errMsg: "not found"},
+				{url: "/one", errMsg: "page has 0 links, no link 2"},
+			},
+		},
+	}
+
+	client := newTestClient()
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var urls []string
+			for _, p := range tc.paths {
+				urls = append(urls, svr.URL+p)
+			}
+
+			var got []result
+			for res := range fetchAll(client, urls, tc.parallel, tc.link) {
+				got = append(got, res)
+			}
+
+			if len(got) != len(tc.expected) {
+				t.Fatalf("got %d results, want %d", len(got), len(tc.expected))
+			}
+			for i, res := range got {
+				want := tc.expected[i]
+				if res.url != svr.URL+want.url {
+					t.Errorf("result %d: got URL '%s', want '%s'", i, res.url, svr.URL+want.url)
+				}
+				if want.errMsg == "" && res.err != nil {
+					t.Errorf("result %d: unexpected error: %q", i, res.err)
+				}
+				if want.errMsg != "" && (res.err == nil || !strings.Contains(res.err.Error(), want.errMsg)) {
+					t.Errorf("result %d: got error '%v', want '%s'", i, res.err, want.errMsg)
+				}
+				status := 0
+				if res.resp != nil {
+					status = res.resp.StatusCode
+				}
+				if status != want.status {
+					t.Errorf("result %d: got status %d, want %d", i, status, want.status)
+				}
+			}
+		})
+	}
+}
+
+func TestOutputPath(t *testing.T) {
+	testCases := map[string]struct {
+		urls     []string
+		expected []string
+	}{
+		"page": {
+			urls:     []string{"gemini://some.url/a/b.gmi"},
+			expected: []string{"some.url_a_b.gmi"},
+		},
+		"no scheme": {
+			urls:     []string{"some.url/a"},
+			expected: []string{"some.url_a"},
+		},
+		"directory": {
+			urls:     []string{"gemini://some.url/a/", "gemini://some.url/"},
+			expected: []string{"some.url_a_index.gmi", "some.url_index.gmi"},
+		},
+		"query": {
+			urls:     []string{"gemini://some.url/search?a/b"},
+			expected: []string{"some.url_search_a_b"},
+		},
+		"directory and index page": {
+			urls:     []string{"gemini://some.url/a/", "gemini://some.url/a/index.gmi"},
+			expected: []string{"some.url_a_index.gmi", "some.url_a_index_2.gmi"},
+		},
+		"queries": {
+			urls:     []string{"gemini://some.url/a?x/y", "gemini://some.url/a?x_y", "gemini://some.url/a_x_y"},
+			expected: []string{"some.url_a_x_y", "some.url_a_x_y_2", "some.url_a_x_y_3"},
+		},
+		"same URL twice": {
+			urls:     []string{"gemini://some.url/a.gmi", "gemini://some.url/a.gmi"},
+			expected: []string{"some.url_a.gmi", "some.url_a_2.gmi"},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			used := make(map[string]bool)
+			for i, u := range tc.urls {
+				want := filepath.Join("out", tc.expected[i])
+				if got := outputPath("out", u, used); got != want {
+					t.Errorf("got path '%s' for '%s', want '%s'", got, u, want)
+				}
+			}
+		})
+	}
+}
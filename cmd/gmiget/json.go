@@ -0,0 +1,135 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/chriswalker/gmi-utils/gemini"
+)
+
+// jsonResponse is the machine-readable representation of a fetch
+// result, emitted with -json.
+type jsonResponse struct {
+	URL           string            `json:"url"`
+	FinalURL      string            `json:"final_url,omitempty"`
+	Status        int               `json:"status,omitempty"`
+	StatusText    string            `json:"status_text,omitempty"`
+	Header        string            `json:"header,omitempty"`
+	Meta          string            `json:"meta,omitempty"`
+	MediaType     string            `json:"media_type,omitempty"`
+	MediaParams   map[string]string `json:"media_type_params,omitempty"`
+	ContentLength int               `json:"content_length"`
+	DurationMS    float64           `json:"response_duration_ms"`
+	TLS           *jsonTLS          `json:"tls,omitempty"`
+	Body          string            `json:"body,omitempty"`
+	BodyEncoding  string            `json:"body_encoding,omitempty"`
+	Error         string            `json:"error,omitempty"`
+}
+
+// jsonTLS describes the TLS connection a response was received over.
+type jsonTLS struct {
+	Version      string            `json:"version"`
+	CipherSuite  string            `json:"cipher_suite"`
+	ServerName   string            `json:"server_name,omitempty"`
+	Certificates []jsonCertificate `json:"certificates,omitempty"`
+}
+
+// jsonCertificate describes a single certificate in the chain
+// presented by the server.
+type jsonCertificate struct {
+	Subject   string    `json:"subject"`
+	Issuer    string    `json:"issuer"`
+	NotBefore time.Time `json:"not_before"`
+	NotAfter  time.Time `json:"not_after"`
+	SHA256    string    `json:"sha256"`
+}
+
+// newJSONResponse builds the JSON representation of the supplied
+// fetch result.
+func newJSONResponse(res result) jsonResponse {
+	j := jsonResponse{URL: res.url}
+	if res.err != nil {
+		j.Error = res.err.Error()
+	}
+
+	rsp := res.resp
+	if rsp == nil {
+		return j
+	}
+
+	j.FinalURL = rsp.URL.String()
+	j.Status = rsp.StatusCode
+	j.StatusText = gemini.StatusText(rsp.StatusCode)
+	j.Header = rsp.Header
+	j.Meta = rsp.Meta
+	j.ContentLength = rsp.ContentLength
+	j.DurationMS = float64(rsp.ResponseDuration) / float64(time.Millisecond)
+	if mediaType, params, err := rsp.MediaType(); err == nil {
+		j.MediaType = mediaType
+		if len(params) > 0 {
+			j.MediaParams = params
+		}
+	}
+
+	if rsp.TLS != nil {
+		j.TLS = newJSONTLS(rsp.TLS)
+	}
+
+	if len(rsp.Body) > 0 {
+		// Textual bodies are output as-is; anything else is
+		// base64-encoded
+		if strings.HasPrefix(j.MediaType, "text/") && utf8.Valid(rsp.Body) {
+			j.Body = string(rsp.Body)
+			j.BodyEncoding = "utf-8"
+		} else {
+			j.Body = base64.StdEncoding.EncodeToString(rsp.Body)
+			j.BodyEncoding = "base64"
+		}
+	}
+
+	return j
+}
+
+// newJSONTLS builds the JSON representation of a TLS connection state.
+func newJSONTLS(state *tls.ConnectionState) *jsonTLS {
+	t := &jsonTLS{
+		Version:     tlsVersionName(state.Version),
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+		ServerName:  state.ServerName,
+	}
+
+	for _, cert := range state.PeerCertificates {
+		sum := sha256.Sum256(cert.Raw)
+		t.Certificates = append(t.Certificates, jsonCertificate{
+			Subject:   cert.Subject.String(),
+			Issuer:    cert.Issuer.String(),
+			NotBefore: cert.NotBefore,
+			NotAfter:  cert.NotAfter,
+			SHA256:    hex.EncodeToString(sum[:]),
+		})
+	}
+
+	return t
+}
+
+// tlsVersionName returns the name of the supplied TLS version.
+func tlsVersionName(version uint16) string {
+	switch version {
+	case tls.VersionTLS10:
+		return "TLS 1.0"
+	case tls.VersionTLS11:
+		return "TLS 1.1"
+	case tls.VersionTLS12:
+		return "TLS 1.2"
+	case tls.VersionTLS13:
+		return "TLS 1.3"
+	default:
+		return fmt.Sprintf("0x%04x", version)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chriswalker/gmi-utils/gemini"
+	"github.com/chriswalker/gmi-utils/gemini/geminitest"
+)
+
+// newTestClient returns a client for fetching from a geminitest server.
+func newTestClient() *gemini.Client {
+	return gemini.NewClient(
+		gemini.Timeout(5*time.Second),
+		gemini.Config(&tls.Config{InsecureSkipVerify: true}),
+	)
+}
+
+func TestNewJSONResponse(t *testing.T) {
+	png := "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+	svr, err := geminitest.NewServer(map[string]string{
+		"/page.gmi":  "20 text/gemini; lang=en\r\n# Page\n",
+		"/image.png": "20 image/png\r\n" + png,
+		"/empty":     "20\r\n# No MIME type\n",
+		"/busy":      "40 server busy\r\n",
+	})
+	if err != nil {
+		t.Fatal("unable to start test server:", err)
+	}
+	defer svr.Close()
+
+	testCases := map[string]struct {
+		path         string
+		status       int
+		meta         string
+		mediaType    string
+		body         string
+		bodyEncoding string
+		err          string
+	}{
+		"gemtext": {
+			path:         "/page.gmi",
+			status:       gemini.StatusSuccess,
+			meta:         "text/gemini; lang=en",
+			mediaType:    "text/gemini",
+			body:         "# Page\n",
+			bodyEncoding: "utf-8",
+		},
+		"binary": {
+			path:         "/image.png",
+			status:       gemini.StatusSuccess,
+			meta:         "image/png",
+			mediaType:    "image/png",
+			body:         base64.StdEncoding.EncodeToString([]byte(png)),
+			bodyEncoding: "base64",
+		},
+		"empty meta": {
+			path:         "/empty",
+			status:       gemini.StatusSuccess,
+			mediaType:    "text/gemini",
+			body:         "# No MIME type\n",
+			bodyEncoding: "utf-8",
+		},
+		"failure": {
+			path:   "/busy",
+			status: gemini.StatusTemporaryFailure,
+			meta:   "server busy",
+			err:    "server busy",
+		},
+	}
+
+	client := newTestClient()
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			j := newJSONResponse(get(client, svr.URL+tc.path, 0))
+
+			if j.Status != tc.status {
+				t.Errorf("got status %d, want %d", j.Status, tc.status)
+			}
+			if j.Meta != tc.meta {
+				t.Errorf("got meta '%s', want '%s'", j.Meta, tc.meta)
+			}
+			if j.MediaType != tc.mediaType {
+				t.Errorf("got media type '%s', want '%s'", j.MediaType, tc.mediaType)
+			}
+			if j.Body != tc.body {
+				t.Errorf("got body '%s', want '%s'", j.Body, tc.body)
+			}
+			if j.BodyEncoding != tc.bodyEncoding {
+				t.Errorf("got body encoding '%s', want '%s'", j.BodyEncoding, tc.bodyEncoding)
+			}
+			if !strings.Contains(j.Error, tc.err) || (tc.err == "") != (j.Error == "") {
+				t.Errorf("got error '%s', want '%s'", j.Error, tc.err)
+			}
+		})
+	}
+}
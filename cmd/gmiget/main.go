@@ -1,12 +1,17 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/chriswalker/gmi-utils/cli"
 	"github.com/chriswalker/gmi-utils/gemini"
@@ -14,18 +19,43 @@ import (
 
 const (
 	desc  = "gmiget - gets Gemini pages"
-	usage = `  gmiget [flags...] <url>`
+	usage = `  gmiget [flags...] <url> [<url>...]
+
+  # Fetch newline-separated URLs from stdin, eight at a time
+  cat urls.txt | gmiget -p 8 -o pages/
+
+  # Follow the third link on a page
+  gmiget -link 3 gemini://some-url/ | gmifmt -refs end`
 )
 
 var (
 	help       bool
-	statusOnly bool
+	headerOnly bool
+	include    bool
+	noFollow   bool
+	jsonOutput bool
+	parallel   int
+	outputDir  string
+	link       int
 )
 
 func main() {
 	flag.BoolVar(&help, "help", false, "Show help for gmiget")
 	flag.BoolVar(&help, "h", false, "Show help for gmiget")
-	flag.BoolVar(&statusOnly, "I", false, "Output response header only")
+	flag.BoolVar(&headerOnly, "I", false, "Output raw response header only")
+	flag.BoolVar(&include, "include", false, "Output raw response header before the body")
+	flag.BoolVar(&include, "i", false, "Output raw response header before the body")
+	flag.BoolVar(&noFollow, "no-follow", false, "Do not follow redirects")
+	flag.BoolVar(&jsonOutput, "json", false, "Output each response as a JSON object")
+	flag.IntVar(&parallel, "parallel", 4, "Number of URLs to fetch concurrently")
+	flag.IntVar(&parallel, "p", 4, "Number of URLs to fetch concurrently")
+	flag.StringVar(&outputDir, "output-dir", "", "Directory to write each fetched page to")
+	flag.StringVar(&outputDir, "o", "", "Directory to write each fetched page to")
+	flag.Func("link", "Fetch the page's Nth link, numbered as by gmifmt -number", func(s string) error {
+		n, err := parseLink(s)
+		link = n
+		return err
+	})
 
 	flag.Usage = cli.Usage(cli.UsageOptions{
 		Description: desc,
@@ -38,52 +68,151 @@ func main() {
 		os.Exit(1)
 	}
 
-	geminiURL, err := getURL(os.Stdin)
+	urls, err := getURLs(os.Stdin)
 	if err != nil {
-		fmt.Fprint(os.Stderr, err.Error())
+		fmt.Fprintf(os.Stderr, "gmiget: %s\n", err)
 		os.Exit(1)
 	}
 
-	resp, err := gemini.Get(geminiURL)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "gmiget: could not open URL: %s\n", err)
+	if outputDir != "" {
+		if err := os.MkdirAll(outputDir, 0755); err != nil {
+			fmt.Fprintf(os.Stderr, "gmiget: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	client := gemini.NewClient(
+		gemini.Timeout(9*time.Second),
+		gemini.Config(&tls.Config{InsecureSkipVerify: true}),
+		gemini.FollowRedirects(!noFollow),
+	)
+
+	failed := 0
+	paths := make(map[string]bool)
+	for res := range fetchAll(client, urls, parallel, link) {
+		ok := true
+		if res.err != nil {
+			fmt.Fprintf(os.Stderr, "gmiget: could not open URL '%s': %s\n", res.url, res.err)
+			ok = false
+		}
+		if err := output(res, len(urls) > 1, paths); err != nil {
+			fmt.Fprintf(os.Stderr, "gmiget: %s\n", err)
+			ok = false
+		}
+		if !ok {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		if len(urls) > 1 {
+			fmt.Fprintf(os.Stderr, "gmiget: %d of %d URLs failed\n", failed, len(urls))
+		}
 		os.Exit(1)
 	}
+}
+
+// output emits a single fetch result, either to its own file in the
+// output directory or to stdout. When delimit is set, stdout output
+// is preceded by a header line naming the URL, so several pages can
+// be told apart in a single stream. paths records the files already
+// written, so no two results share one.
+func output(res result, delimit bool, paths map[string]bool) error {
+	body := format(res)
+	if body == nil {
+		return nil
+	}
+
+	if outputDir != "" {
+		return os.WriteFile(outputPath(outputDir, res.url, paths), body, 0644)
+	}
 
-	output := string(resp.Body)
-	if statusOnly {
-		output = gemini.Status(resp.StatusCode)
+	if delimit && !jsonOutput {
+		fmt.Printf("==> %s <==\n", res.url)
 	}
-	fmt.Printf("%s\n", output)
+	_, err := os.Stdout.Write(body)
+	return err
 }
 
-// getURL gets a URL from either stdin (if being piped in) or from the
-// command line via args. It returns an error if a URL is not supplied.
-func getURL(in *os.File) (string, error) {
-	var url string
+// format builds the output for a single fetch result, according to
+// the output flags. It returns nil if there is nothing to output.
+func format(res result) []byte {
+	var b bytes.Buffer
+
+	// JSON output covers failures too, connection errors included
+	if jsonOutput {
+		enc := json.NewEncoder(&b)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(newJSONResponse(res)); err != nil {
+			return nil
+		}
+		return b.Bytes()
+	}
+
+	if res.resp == nil {
+		return nil
+	}
+
+	switch {
+	case headerOnly:
+		fmt.Fprintln(&b, res.resp.Header)
+	case res.err != nil:
+		// Unhandled statuses still carry a response; show its
+		// header if asked for one
+		if !include {
+			return nil
+		}
+		fmt.Fprintln(&b, res.resp.Header)
+	case include:
+		fmt.Fprintf(&b, "%s\n%s\n", res.resp.Header, res.resp.Body)
+	default:
+		fmt.Fprintf(&b, "%s\n", res.resp.Body)
+	}
+
+	return b.Bytes()
+}
+
+// getURLs gets URLs from either stdin (if being piped in, one per line)
+// or from the command line via args. It returns an error if no URLs are
+// supplied.
+func getURLs(in *os.File) ([]string, error) {
+	var urls []string
 
 	f, err := in.Stat()
 	if err != nil {
-		return "", nil
+		return nil, err
 	}
 
-	// URL must either be passed in via a pipe, or flag
+	// URLs must either be passed in via a pipe, or args
 	if f.Mode()&os.ModeNamedPipe != 0 {
-		// Got something, read it
-		// TODO - this needs a little refactoring
-		b, err := io.ReadAll(os.Stdin)
+		urls, err = readURLs(in)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
-		url = string(b)
-		url = strings.TrimRight(url, "\n")
 	} else {
-		args := flag.Args()
-		if len(args) != 1 {
-			return "", errors.New("gmiget: missing Gemini URL")
+		urls = flag.Args()
+	}
+
+	if len(urls) == 0 {
+		return nil, errors.New("missing Gemini URL")
+	}
+
+	return urls, nil
+}
+
+// readURLs reads newline-separated URLs from the supplied reader,
+// ignoring blank lines.
+func readURLs(r io.Reader) ([]string, error) {
+	var urls []string
+
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		url := strings.TrimSpace(s.Text())
+		if url == "" {
+			continue
 		}
-		url = args[0]
+		urls = append(urls, url)
 	}
 
-	return url, nil
+	return urls, s.Err()
 }
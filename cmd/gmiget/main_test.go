@@ -0,0 +1,38 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadURLs(t *testing.T) {
+	testCases := map[string]struct {
+		input    string
+		expected []string
+	}{
+		"one per line": {
+			input:    "gemini://some.url/\ngemini://other.url/a.gmi\n",
+			expected: []string{"gemini://some.url/", "gemini://other.url/a.gmi"},
+		},
+		"blank lines and whitespace": {
+			input:    "\n  some.url/a \r\n\t\nsome.url/b",
+			expected: []string{"some.url/a", "some.url/b"},
+		},
+		"empty": {
+			input: "",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			got, err := readURLs(strings.NewReader(tc.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("got %q, want %q", got, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/chriswalker/gmi-utils/gemtext"
+)
+
+// filter selects the links to output.
+type filter struct {
+	// Schemes and hosts to keep; if empty, links are kept whatever
+	// their scheme or host
+	schemes map[string]bool
+	hosts   map[string]bool
+	// Keep only relative or only absolute links
+	relative bool
+	absolute bool
+	// Keep only links whose URL or label matches
+	match *regexp.Regexp
+	// Drop links whose URL has already been output
+	dedupe bool
+	seen   map[string]bool
+}
+
+// newFilter creates a filter from the supplied comma-separated lists
+// of schemes and hosts.
+func newFilter(schemes, hosts string) *filter {
+	return &filter{
+		schemes: set(schemes),
+		hosts:   set(hosts),
+		seen:    make(map[string]bool),
+	}
+}
+
+// keep reports whether the supplied link passes the filter.
+func (f *filter) keep(link gemtext.PageLink) bool {
+	if f.relative && !link.Relative || f.absolute && link.Relative {
+		return false
+	}
+	if f.match != nil && !f.match.MatchString(link.URL) && !f.match.MatchString(link.Label) {
+		return false
+	}
+
+	if len(f.schemes) > 0 || len(f.hosts) > 0 {
+		u, err := url.Parse(link.URL)
+		if err != nil {
+			return false
+		}
+		// Unresolved relative links share the page's scheme, which
+		// is taken to be Gemini
+		scheme := u.Scheme
+		if scheme == "" {
+			scheme = "gemini"
+		}
+		if len(f.schemes) > 0 && !f.schemes[scheme] {
+			return false
+		}
+		if len(f.hosts) > 0 && !f.hosts[strings.ToLower(u.Hostname())] {
+			return false
+		}
+	}
+
+	if f.dedupe {
+		if f.seen[link.URL] {
+			return false
+		}
+		f.seen[link.URL] = true
+	}
+
+	return true
+}
+
+// set builds a set of the lowercased values in the supplied
+// comma-separated list.
+func set(list string) map[string]bool {
+	s := make(map[string]bool)
+	for _, v := range strings.Split(list, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			s[strings.ToLower(v)] = true
+		}
+	}
+
+	return s
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/chriswalker/gmi-utils/gemtext"
+)
+
+func TestFilterKeep(t *testing.T) {
+	links := []gemtext.PageLink{
+		{URL: "gemini://some.url/a.gmi", Label: "Alpha"},
+		{URL: "/b.gmi", Label: "Beta", Relative: true},
+		{URL: "gemini://OTHER.url/c.gmi", Label: "Gamma"},
+		{URL: "https://some.url/d", Label: "Delta"},
+		{URL: "gemini://some.url/a.gmi", Label: "Alpha again"},
+		{URL: "%zz", Label: "Invalid"},
+	}
+
+	testCases := map[string]struct {
+		schemes  string
+		hosts    string
+		relative bool
+		absolute bool
+		match    string
+		dedupe   bool
+		expected []string
+	}{
+		"no filters": {
+			expected: []string{"Alpha", "Beta", "Gamma", "Delta", "Alpha again", "Invalid"},
+		},
+		"scheme": {
+			schemes: "gemini",
+			// Relative links are taken to be Gemini links
+			expected: []string{"Alpha", "Beta", "Gamma", "Alpha again"},
+		},
+		"schemes list": {
+			schemes:  " HTTPS, gopher ",
+			expected: []string{"Delta"},
+		},
+		"hosts": {
+			hosts:    "other.url,nowhere",
+			expected: []string{"Gamma"},
+		},
+		"scheme and host": {
+			schemes:  "gemini",
+			hosts:    "some.url",
+			expected: []string{"Alpha", "Alpha again"},
+		},
+		"relative": {
+			relative: true,
+			expected: []string{"Beta"},
+		},
+		"absolute": {
+			absolute: true,
+			expected: []string{"Alpha", "Gamma", "Delta", "Alpha again", "Invalid"},
+		},
+		"match URL": {
+			match:    `\.gmi$`,
+			expected: []string{"Alpha", "Beta", "Gamma", "Alpha again"},
+		},
+		"match label": {
+			match:    "^(Beta|Delta)$",
+			expected: []string{"Beta", "Delta"},
+		},
+		"dedupe": {
+			dedupe:   true,
+			expected: []string{"Alpha", "Beta", "Gamma", "Delta", "Invalid"},
+		},
+		"combined": {
+			schemes:  "gemini",
+			absolute: true,
+			match:    "a",
+			dedupe:   true,
+			expected: []string{"Alpha", "Gamma"},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			f := newFilter(tc.schemes, tc.hosts)
+			f.relative = tc.relative
+			f.absolute = tc.absolute
+			f.dedupe = tc.dedupe
+			if tc.match != "" {
+				f.match = regexp.MustCompile(tc.match)
+			}
+
+			var got []string
+			for _, l := range links {
+				if f.keep(l) {
+					got = append(got, l.Label)
+				}
+			}
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("got %v, want %v", got, tc.expected)
+			}
+		})
+	}
+}
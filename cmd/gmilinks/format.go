@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/chriswalker/gmi-utils/gemtext"
+)
+
+// writer outputs links in a particular format.
+type writer interface {
+	write(link gemtext.PageLink) error
+	// close finishes the output, once all links are written
+	close() error
+}
+
+// newWriter returns a writer for the named format: one of pipe, tsv,
+// json, jsonl, csv or gemtext. Any other format containing "{{" is
+// taken as a Go template, executed for each link. If markExternal is
+// set, the labels of external links are marked with an arrow in the
+// pipe and gemtext formats; the others hold an external field instead.
+func newWriter(format string, w io.Writer, markExternal bool) (writer, error) {
+	label := func(l gemtext.PageLink) string {
+		if markExternal && l.External {
+			return strings.TrimSpace(l.Label + " " + externalMarker)
+		}
+		return l.Label
+	}
+
+	switch format {
+	case "pipe":
+		return lineWriter{w: w, format: func(l gemtext.PageLink) string {
+			return label(l) + "|" + l.URL
+		}}, nil
+	case "tsv":
+		return lineWriter{w: w, format: func(l gemtext.PageLink) string {
+			return strings.Join(fields(l, tsvField), "\t")
+		}}, nil
+	case "gemtext":
+		return lineWriter{w: w, format: func(l gemtext.PageLink) string {
+			return gemtext.Link{URL: l.URL, Label: label(l)}.String()
+		}}, nil
+	case "jsonl":
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		return &jsonWriter{enc: enc, lines: true}, nil
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		return &jsonWriter{enc: enc}, nil
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write(fieldNames); err != nil {
+			return nil, err
+		}
+		return csvWriter{w: cw}, nil
+	}
+
+	if !strings.Contains(format, "{{") {
+		return nil, fmt.Errorf("unknown format '%s'", format)
+	}
+	tmpl, err := template.New("link").Parse(format)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing format template: %w", err)
+	}
+	return templateWriter{w: w, tmpl: tmpl}, nil
+}
+
+// externalMarker marks the labels of external links, as gmifmt does.
+const externalMarker = "↗"
+
+// fieldNames names the fields output for each link in tabular formats.
+var fieldNames = []string{"url", "label", "line", "heading", "position", "relative", "external"}
+
+// fields returns the values of each of a link's fields, in the order
+// of fieldNames, passing each through the supplied escaping function.
+func fields(l gemtext.PageLink, escape func(string) string) []string {
+	values := []string{
+		l.URL,
+		l.Label,
+		strconv.Itoa(l.Line),
+		l.Heading,
+		strconv.Itoa(l.Position),
+		strconv.FormatBool(l.Relative),
+		strconv.FormatBool(l.External),
+	}
+	for i, v := range values {
+		values[i] = escape(v)
+	}
+
+	return values
+}
+
+// tsvField makes the supplied value safe for a TSV field, replacing
+// any tabs with spaces.
+func tsvField(s string) string {
+	return strings.ReplaceAll(s, "\t", " ")
+}
+
+// lineWriter writes each link as a single line of text.
+type lineWriter struct {
+	w      io.Writer
+	format func(gemtext.PageLink) string
+}
+
+func (lw lineWriter) write(l gemtext.PageLink) error {
+	_, err := fmt.Fprintln(lw.w, lw.format(l))
+	return err
+}
+
+func (lw lineWriter) close() error {
+	return nil
+}
+
+// jsonLink is the JSON representation of a link.
+type jsonLink struct {
+	URL      string `json:"url"`
+	Label    string `json:"label,omitempty"`
+	Line     int    `json:"line"`
+	Heading  string `json:"heading,omitempty"`
+	Position int    `json:"position"`
+	Relative bool   `json:"relative"`
+	External bool   `json:"external"`
+}
+
+// jsonWriter writes links as JSON; either a single array once all
+// links are written, or one object per line.
+type jsonWriter struct {
+	enc   *json.Encoder
+	lines bool
+	links []jsonLink
+}
+
+func (jw *jsonWriter) write(l gemtext.PageLink) error {
+	link := jsonLink(l)
+	if jw.lines {
+		return jw.enc.Encode(link)
+	}
+	jw.links = append(jw.links, link)
+
+	return nil
+}
+
+func (jw *jsonWriter) close() error {
+	if jw.lines {
+		return nil
+	}
+	// Always output an array, even if there are no links
+	if jw.links == nil {
+		jw.links = []jsonLink{}
+	}
+
+	return jw.enc.Encode(jw.links)
+}
+
+// csvWriter writes links as CSV records, following a header record.
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (cw csvWriter) write(l gemtext.PageLink) error {
+	return cw.w.Write(fields(l, func(s string) string { return s }))
+}
+
+func (cw csvWriter) close() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// templateWriter writes each link by executing a template, followed
+// by a newline.
+type templateWriter struct {
+	w    io.Writer
+	tmpl *template.Template
+}
+
+func (tw templateWriter) write(l gemtext.PageLink) error {
+	if err := tw.tmpl.Execute(tw.w, l); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(tw.w)
+	return err
+}
+
+func (tw templateWriter) close() error {
+	return nil
+}
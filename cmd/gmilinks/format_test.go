@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/chriswalker/gmi-utils/gemtext"
+)
+
+// testLinks are output in each format.
+var testLinks = []gemtext.PageLink{
+	{URL: "gemini://some.url/a.gmi", Label: "A", Line: 2, Heading: "Links", Position: 1},
+	{URL: "/b\tc.gmi", Label: "Tab\tand, \"quotes\"", Line: 3, Heading: "Links", Position: 2, Relative: true},
+	{URL: "gemini://other.url/", Line: 5, Position: 1, External: true},
+}
+
+func TestNewWriter(t *testing.T) {
+	testCases := map[string]struct {
+		format       string
+		markExternal bool
+		expected     string
+		errMsg       string
+	}{
+		"pipe": {
+			format:   "pipe",
+			expected: "A|gemini://some.url/a.gmi\nTab\tand, \"quotes\"|/b\tc.gmi\n|gemini://other.url/\n",
+		},
+		"pipe marking external links": {
+			format:       "pipe",
+			markExternal: true,
+			expected:     "A|gemini://some.url/a.gmi\nTab\tand, \"quotes\"|/b\tc.gmi\n↗|gemini://other.url/\n",
+		},
+		"tsv": {
+			format: "tsv",
+			expected: "gemini://some.url/a.gmi\tA\t2\tLinks\t1\tfalse\tfalse\n" +
+				"/b c.gmi\tTab and, \"quotes\"\t3\tLinks\t2\ttrue\tfalse\n" +
+				"gemini://other.url/\t\t5\t\t1\tfalse\ttrue\n",
+		},
+		"tsv ignores marking": {
+			format:       "tsv",
+			markExternal: true,
+			expected: "gemini://some.url/a.gmi\tA\t2\tLinks\t1\tfalse\tfalse\n" +
+				"/b c.gmi\tTab and, \"quotes\"\t3\tLinks\t2\ttrue\tfalse\n" +
+				"gemini://other.url/\t\t5\t\t1\tfalse\ttrue\n",
+		},
+		"csv": {
+			format: "csv",
+			expected: "url,label,line,heading,position,relative,external\n" +
+				"gemini://some.url/a.gmi,A,2,Links,1,false,false\n" +
+				"/b\tc.gmi,\"Tab\tand, \"\"quotes\"\"\",3,Links,2,true,false\n" +
+				"gemini://other.url/,,5,,1,false,true\n",
+		},
+		"json": {
+			format: "json",
+			expected: `[
+  {
+    "url": "gemini://some.url/a.gmi",
+    "label": "A",
+    "line": 2,
+    "heading": "Links",
+    "position": 1,
+    "relative": false,
+    "external": false
+  },
+  {
+    "url": "/b\tc.gmi",
+    "label": "Tab\tand, \"quotes\"",
+    "line": 3,
+    "heading": "Links",
+    "position": 2,
+    "relative": true,
+    "external": false
+  },
+  {
+    "url": "gemini://other.url/",
+    "line": 5,
+    "position": 1,
+    "relative": false,
+    "external": true
+  }
+]
+`,
+		},
+		"jsonl": {
+			format: "jsonl",
+			expected: `{"url":"gemini://some.url/a.gmi","label":"A","line":2,"heading":"Links","position":1,"relative":false,"external":false}` + "\n" +
+				`{"url":"/b\tc.gmi","label":"Tab\tand, \"quotes\"","line":3,"heading":"Links","position":2,"relative":true,"external":false}` + "\n" +
+				`{"url":"gemini://other.url/","line":5,"position":1,"relative":false,"external":true}` + "\n",
+		},
+		"gemtext": {
+			format:       "gemtext",
+			markExternal: true,
+			expected:     "=> gemini://some.url/a.gmi A\n=> /b\tc.gmi Tab\tand, \"quotes\"\n=> gemini://other.url/ ↗\n",
+		},
+		"template": {
+			format:   "{{.Line}}: {{.URL}}{{if .External}} (external){{end}}",
+			expected: "2: gemini://some.url/a.gmi\n3: /b\tc.gmi\n5: gemini://other.url/ (external)\n",
+		},
+		"unknown format": {
+			format: "xml",
+			errMsg: "unknown format 'xml'",
+		},
+		"invalid template": {
+			format: "{{.URL",
+			errMsg: "error parsing format template",
+		},
+		"template execution error": {
+			format: "{{.Missing}}",
+			errMsg: "can't evaluate field Missing",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var got bytes.Buffer
+			err := writeLinks(tc.format, &got, tc.markExternal)
+
+			if tc.errMsg != "" {
+				if err == nil {
+					t.Fatalf("expected error '%s', got nil", tc.errMsg)
+				}
+				if !strings.Contains(err.Error(), tc.errMsg) {
+					t.Errorf("got error '%s', want '%s'", err, tc.errMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+			if got.String() != tc.expected {
+				t.Errorf("got '%s', want '%s'", got.String(), tc.expected)
+			}
+		})
+	}
+}
+
+func TestNewWriterNoLinks(t *testing.T) {
+	testCases := map[string]string{
+		"pipe":  "",
+		"csv":   "url,label,line,heading,position,relative,external\n",
+		"json":  "[]\n",
+		"jsonl": "",
+	}
+
+	for format, expected := range testCases {
+		t.Run(format, func(t *testing.T) {
+			var got bytes.Buffer
+			w, err := newWriter(format, &got, false)
+			if err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+			if err := w.close(); err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+			if got.String() != expected {
+				t.Errorf("got '%s', want '%s'", got.String(), expected)
+			}
+		})
+	}
+}
+
+// writeLinks writes testLinks to out in the supplied format.
+func writeLinks(format string, out *bytes.Buffer, markExternal bool) error {
+	w, err := newWriter(format, out, markExternal)
+	if err != nil {
+		return err
+	}
+	for _, l := range testLinks {
+		if err := w.write(l); err != nil {
+			return err
+		}
+	}
+
+	return w.close()
+}
@@ -4,7 +4,9 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
+	"regexp"
 
 	"github.com/chriswalker/gmi-utils/cli"
 	"github.com/chriswalker/gmi-utils/gemtext"
@@ -16,12 +18,30 @@ const (
 	usage = `  gmilinks -f <file>,
 
   # Pipe in gemtext via stdin
-  gmiget gemini://some-url/ | gmilinks | fzf`
+  gmiget gemini://some-url/ | gmilinks | fzf
+
+  # Resolve relative links against the page URL
+  gmiget gemini://some-url/ | gmilinks -base gemini://some-url/
+
+  # Output each unique Gemini link on the page as JSON
+  gmiget gemini://some-url/ | gmilinks -scheme gemini -dedupe -format jsonl
+
+  # Output links using a template
+  gmilinks -f page.gmi -format '{{.Line}}: {{.URL}}'`
 )
 
 var (
 	help      bool
 	inputFile string
+	base      string
+	external  bool
+	format    string
+	schemes   string
+	hosts     string
+	relative  bool
+	absolute  bool
+	match     string
+	dedupe    bool
 )
 
 func main() {
@@ -29,6 +49,15 @@ func main() {
 	flag.BoolVar(&help, "h", false, "Show help for gmilinks")
 	flag.StringVar(&inputFile, "file", "", "Gemtext file to extract links from")
 	flag.StringVar(&inputFile, "f", "", "Gemtext file to extract links from")
+	flag.StringVar(&base, "base", "", "URL of the page, to resolve relative links against")
+	flag.BoolVar(&external, "external", false, "Mark links to other capsules than -base")
+	flag.StringVar(&format, "format", "pipe", "Output format: pipe, tsv, json, jsonl, csv, gemtext, or a Go template")
+	flag.StringVar(&schemes, "scheme", "", "Comma-separated URL schemes of links to output")
+	flag.StringVar(&hosts, "host", "", "Comma-separated hosts of links to output")
+	flag.BoolVar(&relative, "relative", false, "Output only relative links")
+	flag.BoolVar(&absolute, "absolute", false, "Output only absolute links")
+	flag.StringVar(&match, "match", "", "Output only links whose URL or label matches this regular expression")
+	flag.BoolVar(&dedupe, "dedupe", false, "Output only the first link to each URL")
 
 	flag.Usage = cli.Usage(cli.UsageOptions{
 		Description: desc,
@@ -66,8 +95,55 @@ func main() {
 		input = os.Stdin
 	}
 
-	links := gemtext.ExtractLinks(input)
-	for link, text := range links {
-		fmt.Printf("%s|%s\n", text, link)
+	var opts []gemtext.Option
+	if base != "" {
+		baseURL, err := url.Parse(base)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
+			os.Exit(1)
+		}
+		opts = append(opts, gemtext.Base(baseURL))
+	}
+
+	if relative && absolute {
+		fmt.Fprintf(os.Stderr, "%s: -relative and -absolute cannot be used together\n", name)
+		os.Exit(1)
+	}
+	filter := newFilter(schemes, hosts)
+	filter.relative = relative
+	filter.absolute = absolute
+	filter.dedupe = dedupe
+	if match != "" {
+		re, err := regexp.Compile(match)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
+			os.Exit(1)
+		}
+		filter.match = re
+	}
+
+	w, err := newWriter(format, os.Stdout, external)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
+		os.Exit(1)
+	}
+
+	links, err := gemtext.ExtractPageLinks(input, opts...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
+		os.Exit(1)
+	}
+	for _, link := range links {
+		if !filter.keep(link) {
+			continue
+		}
+		if err := w.write(link); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
+			os.Exit(1)
+		}
+	}
+	if err := w.close(); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
+		os.Exit(1)
 	}
 }
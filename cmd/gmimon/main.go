@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chriswalker/gmi-utils/cli"
+	"github.com/chriswalker/gmi-utils/gemini"
+)
+
+const (
+	name  = "gmimon"
+	desc  = "gmimon - monitors the availability of Gemini capsules"
+	usage = `  gmimon [flags...] <url> [<url>...]
+
+  # Check targets listed in a file every five minutes, writing a
+  # status page and Prometheus metrics after each check
+  gmimon -t targets.txt -i 5m -s status.gmi -m gemini.prom
+
+Target files hold one URL per line, optionally followed by the expected
+status code (default 20). Redirects are not followed, so redirecting
+URLs should be given an expected status of 30 or 31.`
+)
+
+var (
+	help        bool
+	targetsFile string
+	interval    time.Duration
+	timeout     time.Duration
+	once        bool
+	warnDays    int
+	statusPage  string
+	metricsFile string
+)
+
+func main() {
+	flag.BoolVar(&help, "help", false, "Show help for gmimon")
+	flag.BoolVar(&help, "h", false, "Show help for gmimon")
+	flag.StringVar(&targetsFile, "targets", "", "File listing URLs and expected statuses to monitor")
+	flag.StringVar(&targetsFile, "t", "", "File listing URLs and expected statuses to monitor")
+	flag.DurationVar(&interval, "interval", time.Minute, "Time between checks")
+	flag.DurationVar(&interval, "i", time.Minute, "Time between checks")
+	flag.DurationVar(&timeout, "timeout", 10*time.Second, "Timeout for each check, from connecting to reading the response")
+	flag.BoolVar(&once, "once", false, "Check once and exit, with a non-zero status if any target is down")
+	flag.IntVar(&warnDays, "warn-days", 14, "Warn when a certificate expires within this many days")
+	flag.IntVar(&warnDays, "w", 14, "Warn when a certificate expires within this many days")
+	flag.StringVar(&statusPage, "status-page", "", "Path to write a gemtext status page to")
+	flag.StringVar(&statusPage, "s", "", "Path to write a gemtext status page to")
+	flag.StringVar(&metricsFile, "metrics", "", "Path to write Prometheus text-format metrics to")
+	flag.StringVar(&metricsFile, "m", "", "Path to write Prometheus text-format metrics to")
+
+	flag.Usage = cli.Usage(cli.UsageOptions{
+		Description: desc,
+		Usage:       usage,
+	}, os.Stdout)
+	flag.Parse()
+
+	if help {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	targets, err := getTargets()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
+		os.Exit(1)
+	}
+
+	m := &monitor{
+		client: gemini.NewClient(
+			gemini.Timeout(timeout),
+			gemini.Config(&tls.Config{InsecureSkipVerify: true}),
+			gemini.FollowRedirects(false),
+		),
+		targets: targets,
+		warn:    time.Duration(warnDays) * 24 * time.Hour,
+	}
+
+	for {
+		results := m.check()
+		report(results)
+
+		if once {
+			for _, res := range results {
+				if !res.up() {
+					os.Exit(1)
+				}
+			}
+			return
+		}
+		time.Sleep(interval)
+	}
+}
+
+// report logs problems found in the supplied results to stderr, and
+// writes out the status page and metrics if requested.
+func report(results []result) {
+	for _, res := range results {
+		if !res.up() {
+			fmt.Fprintf(os.Stderr, "%s: %s DOWN %s: %s\n", name,
+				res.checked.Format(time.RFC3339), res.target.url, res.problem())
+		}
+		if res.certWarn {
+			fmt.Fprintf(os.Stderr, "%s: %s WARN %s: certificate expires %s\n", name,
+				res.checked.Format(time.RFC3339), res.target.url, res.certExpiry.Format("2006-01-02"))
+		}
+	}
+
+	if statusPage != "" {
+		if err := writeFile(statusPage, func(w io.Writer) error {
+			return writeStatusPage(w, results)
+		}); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: could not write status page: %s\n", name, err)
+		}
+	}
+	if metricsFile != "" {
+		if err := writeFile(metricsFile, func(w io.Writer) error {
+			return writeMetrics(w, results)
+		}); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: could not write metrics: %s\n", name, err)
+		}
+	}
+}
+
+// getTargets gets the targets to monitor from the targets file, if
+// supplied, and the command line args. It returns an error if no
+// targets are supplied.
+func getTargets() ([]target, error) {
+	var targets []target
+
+	if targetsFile != "" {
+		f, err := os.Open(targetsFile)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		targets, err = readTargets(f)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, url := range flag.Args() {
+		targets = append(targets, target{url: url, status: gemini.StatusSuccess})
+	}
+
+	// Targets are reported by URL, so make sure they're absolute
+	for i, t := range targets {
+		if !strings.Contains(t.url, "://") {
+			targets[i].url = fmt.Sprintf("%s://%s", gemini.Scheme, t.url)
+		}
+	}
+
+	if len(targets) == 0 {
+		return nil, errors.New("no URLs to monitor")
+	}
+
+	return targets, nil
+}
+
+// readTargets parses a targets file; each line holds a URL optionally
+// followed by an expected status code. Blank lines and lines starting
+// with '#' are ignored.
+func readTargets(r io.Reader) ([]target, error) {
+	var targets []target
+
+	s := bufio.NewScanner(r)
+	i := 0
+	for s.Scan() {
+		i++
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		t := target{url: fields[0], status: gemini.StatusSuccess}
+		switch len(fields) {
+		case 1:
+		case 2:
+			status, err := strconv.Atoi(fields[1])
+			if err != nil || gemini.StatusText(status) == "" {
+				return nil, fmt.Errorf("invalid status code at line %d ('%s')", i, fields[1])
+			}
+			t.status = status
+		default:
+			return nil, fmt.Errorf("invalid target at line %d ('%s')", i, s.Text())
+		}
+		targets = append(targets, t)
+	}
+
+	return targets, s.Err()
+}
+
+// writeFile writes a file by way of a temporary file in the same
+// directory, so readers never see a partially-written file.
+func writeFile(path string, write func(w io.Writer) error) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+name+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(0644); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
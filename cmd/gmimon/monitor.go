@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/chriswalker/gmi-utils/gemini"
+)
+
+// target is a URL to monitor, along with the status it is expected
+// to respond with.
+type target struct {
+	url    string
+	status int
+}
+
+// result records the outcome of checking a single target.
+type result struct {
+	target  target
+	checked time.Time
+	// Response details; status is zero if no response was received
+	status  int
+	meta    string
+	latency time.Duration
+	err     error
+	// Expiry time of the server's certificate, and whether it falls
+	// within the warning period
+	certExpiry time.Time
+	certWarn   bool
+}
+
+// up reports whether the target responded with its expected status.
+// Any success status counts as the expected success, whatever the MIME
+// type of the response.
+func (r result) up() bool {
+	if isSuccess(r.target.status) {
+		return isSuccess(r.status)
+	}
+	return r.status == r.target.status
+}
+
+// isSuccess reports whether the supplied status is a 2x success status.
+func isSuccess(status int) bool {
+	return status/10 == gemini.StatusSuccess/10
+}
+
+// problem describes why a target is considered down.
+func (r result) problem() string {
+	if r.status == 0 {
+		return r.err.Error()
+	}
+	return fmt.Sprintf("got status %s, want %s", gemini.Status(r.status), gemini.Status(r.target.status))
+}
+
+// monitor checks a set of targets using its client.
+type monitor struct {
+	client  *gemini.Client
+	targets []target
+	// Certificates expiring within this period are warned about
+	warn time.Duration
+}
+
+// check checks all targets concurrently, returning results in the
+// order the targets were supplied.
+func (m *monitor) check() []result {
+	results := make([]result, len(m.targets))
+
+	var wg sync.WaitGroup
+	for i, t := range m.targets {
+		wg.Add(1)
+		go func(i int, t target) {
+			defer wg.Done()
+			results[i] = m.checkTarget(t)
+		}(i, t)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// checkTarget checks a single target.
+func (m *monitor) checkTarget(t target) result {
+	res := result{target: t, checked: time.Now()}
+
+	// Responses with unhandled statuses come back with an error;
+	// only the status matters here, so just record it
+	rsp, err := m.client.Get(t.url)
+	res.err = err
+	if rsp == nil {
+		return res
+	}
+
+	res.status = rsp.StatusCode
+	res.meta = rsp.Meta
+	res.latency = rsp.ResponseDuration
+
+	if rsp.TLS != nil && len(rsp.TLS.PeerCertificates) > 0 {
+		res.certExpiry = rsp.TLS.PeerCertificates[0].NotAfter
+		res.certWarn = res.certExpiry.Sub(res.checked) < m.warn
+	}
+
+	return res
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"crypto/tls"
+	"testing"
+	"time"
+
+	"github.com/chriswalker/gmi-utils/gemini"
+	"github.com/chriswalker/gmi-utils/gemini/geminitest"
+)
+
+func TestCheckTarget(t *testing.T) {
+	svr, err := geminitest.NewServer(map[string]string{
+		"/":          "20 text/gemini\r\n# Capsule\n",
+		"/image.png": "20 image/png\r\n\x89PNG\r\n\x1a\n",
+		"/notes.txt": "20 text/plain\r\nNotes\n",
+		"/old-page":  "31 /new-page\r\n",
+		"/busy":      "40 server busy\r\n",
+	})
+	if err != nil {
+		t.Fatal("unable to start test server:", err)
+	}
+	defer svr.Close()
+
+	testCases := map[string]struct {
+		path     string
+		expected int
+		status   int
+		up       bool
+	}{
+		"gemtext": {
+			path:     "/",
+			expected: gemini.StatusSuccess,
+			status:   gemini.StatusSuccess,
+			up:       true,
+		},
+		"image": {
+			path:     "/image.png",
+			expected: gemini.StatusSuccess,
+			status:   gemini.StatusSuccess,
+			up:       true,
+		},
+		"plain text": {
+			path:     "/notes.txt",
+			expected: gemini.StatusSuccess,
+			status:   gemini.StatusSuccess,
+			up:       true,
+		},
+		"expected redirect": {
+			path:     "/old-page",
+			expected: gemini.StatusRedirectPermanent,
+			status:   gemini.StatusRedirectPermanent,
+			up:       true,
+		},
+		"unexpected redirect": {
+			path:     "/old-page",
+			expected: gemini.StatusSuccess,
+			status:   gemini.StatusRedirectPermanent,
+			up:       false,
+		},
+		"failure": {
+			path:     "/busy",
+			expected: gemini.StatusSuccess,
+			status:   gemini.StatusTemporaryFailure,
+			up:       false,
+		},
+	}
+
+	m := &monitor{
+		client: gemini.NewClient(
+			gemini.Timeout(5*time.Second),
+			gemini.Config(&tls.Config{InsecureSkipVerify: true}),
+			gemini.FollowRedirects(false),
+		),
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			res := m.checkTarget(target{url: svr.URL + tc.path, status: tc.expected})
+
+			if res.status != tc.status {
+				t.Errorf("got status %d, want %d", res.status, tc.status)
+			}
+			if res.up() != tc.up {
+				t.Errorf("got up %t, want %t (%v)", res.up(), tc.up, res.err)
+			}
+			if res.certExpiry.IsZero() {
+				t.Error("expected certificate expiry to be recorded")
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chriswalker/gmi-utils/gemini"
+)
+
+// writeStatusPage writes a gemtext page summarising the supplied
+// results.
+func writeStatusPage(w io.Writer, results []result) error {
+	var b strings.Builder
+
+	up := 0
+	for _, res := range results {
+		if res.up() {
+			up++
+		}
+	}
+
+	b.WriteString("# Capsule status\n\n")
+	if len(results) > 0 {
+		fmt.Fprintf(&b, "Last checked %s. %d of %d up.\n",
+			results[0].checked.UTC().Format("2006-01-02 15:04 MST"), up, len(results))
+	}
+
+	for _, res := range results {
+		state := "up"
+		if !res.up() {
+			state = "DOWN"
+		}
+
+		fmt.Fprintf(&b, "\n## %s\n\n", res.target.url)
+		fmt.Fprintf(&b, "* State: %s\n", state)
+		if res.status != 0 {
+			fmt.Fprintf(&b, "* Response: %s %s\n", gemini.Status(res.status), res.meta)
+			fmt.Fprintf(&b, "* Latency: %s\n", res.latency.Round(time.Microsecond))
+		} else {
+			fmt.Fprintf(&b, "* Error: %s\n", res.err)
+		}
+		if !res.certExpiry.IsZero() {
+			days := int(res.certExpiry.Sub(res.checked).Hours() / 24)
+			warning := ""
+			if res.certWarn {
+				warning = " - renew soon!"
+			}
+			fmt.Fprintf(&b, "* Certificate expires: %s (%d days)%s\n",
+				res.certExpiry.UTC().Format("2006-01-02"), days, warning)
+		}
+		fmt.Fprintf(&b, "\n=> %s Visit\n", res.target.url)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// metric describes a single Prometheus gauge, and how to obtain its
+// value from a result. Results for which ok returns false are omitted.
+type metric struct {
+	name  string
+	help  string
+	value func(res result) (float64, bool)
+}
+
+var metrics = []metric{
+	{
+		name: "gemini_probe_success",
+		help: "Whether the last check returned the expected status.",
+		value: func(res result) (float64, bool) {
+			return boolValue(res.up()), true
+		},
+	},
+	{
+		name: "gemini_probe_status_code",
+		help: "Status code returned by the last check.",
+		value: func(res result) (float64, bool) {
+			return float64(res.status), res.status != 0
+		},
+	},
+	{
+		name: "gemini_probe_duration_seconds",
+		help: "Response time of the last check.",
+		value: func(res result) (float64, bool) {
+			return res.latency.Seconds(), res.status != 0
+		},
+	},
+	{
+		name: "gemini_cert_expiry_timestamp_seconds",
+		help: "Expiry time of the server certificate, as a Unix timestamp.",
+		value: func(res result) (float64, bool) {
+			return float64(res.certExpiry.Unix()), !res.certExpiry.IsZero()
+		},
+	},
+	{
+		name: "gemini_cert_expiry_warning",
+		help: "Whether the server certificate expires within the warning period.",
+		value: func(res result) (float64, bool) {
+			return boolValue(res.certWarn), !res.certExpiry.IsZero()
+		},
+	},
+}
+
+// writeMetrics writes the supplied results as Prometheus text-format
+// metrics, labelled by URL and expected status.
+func writeMetrics(w io.Writer, results []result) error {
+	var b strings.Builder
+
+	for _, m := range metrics {
+		fmt.Fprintf(&b, "# HELP %s %s\n", m.name, m.help)
+		fmt.Fprintf(&b, "# TYPE %s gauge\n", m.name)
+		for _, res := range results {
+			val, ok := m.value(res)
+			if !ok {
+				continue
+			}
+			fmt.Fprintf(&b, "%s{url=\"%s\",expected_status=\"%d\"} %s\n",
+				m.name, escapeLabel(res.target.url), res.target.status,
+				strconv.FormatFloat(val, 'f', -1, 64))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// escapeLabel escapes a Prometheus label value.
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
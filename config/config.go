@@ -3,6 +3,7 @@ package config
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 )
@@ -70,14 +71,19 @@ func Load(file string) (*Config, error) {
 }
 
 // load loads the given file and parses it into a config
-// map.
-func load(file *os.File) (*Config, error) {
+// map. Blank lines, and comments starting with '#', are
+// skipped.
+func load(file io.Reader) (*Config, error) {
 	conf := make(Config)
 
 	s := bufio.NewScanner(file)
 	i := 1
 	for s.Scan() {
 		line := s.Text()
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			i++
+			continue
+		}
 		parts := strings.Split(line, "=")
 		if len(parts) != 2 {
 			return nil, fmt.Errorf("invalid configuration item at line %d ('%s')", i, line)
@@ -2,6 +2,7 @@ package config_test
 
 import (
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -92,3 +93,97 @@ func TestLoad(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadTheme(t *testing.T) {
+	testCases := map[string]struct {
+		name          string
+		xdgConfigHome string
+		homeDir       string
+		expected      config.Config
+		errMsg        string
+	}{
+		"built-in": {
+			name:    "monochrome",
+			homeDir: "./testdata/home2",
+			expected: config.Config{
+				"header":       "bold underline",
+				"header2":      "bold",
+				"header3":      "bold",
+				"preformatted": "dim",
+				"quoted":       "italic",
+				"quotebar":     "dim",
+				"linklabel":    "underline",
+				"linkurl":      "dim",
+				"bullet":       "bold",
+				"keyword":      "bold",
+				"comment":      "italic",
+			},
+		},
+		"from XDG_CONFIG_HOME": {
+			name:          "custom",
+			xdgConfigHome: "./testdata/XDG",
+			homeDir:       "./testdata/home",
+			expected:      config.Config{"header": "#ffffff bold", "link": "#00ff00"},
+		},
+		"overriding built-in": {
+			name:     "nord",
+			homeDir:  "./testdata/home",
+			expected: config.Config{"header": "#000000"},
+		},
+		"invalid theme file": {
+			name:    "broken",
+			homeDir: "./testdata/home",
+			errMsg:  "error loading theme 'broken'",
+		},
+		"unknown": {
+			name:    "custom",
+			homeDir: "./testdata/home",
+			errMsg:  "unknown theme 'custom'",
+		},
+		"invalid name": {
+			name:    "../../.gmifmtconf",
+			homeDir: "./testdata/home",
+			errMsg:  "invalid theme name",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			home, xdg := setup(tc.xdgConfigHome, tc.homeDir)
+			defer reset(home, xdg)
+
+			theme, err := config.LoadTheme(tc.name)
+
+			if tc.errMsg != "" {
+				if err == nil {
+					t.Fatal("expected an error, but got nil")
+				}
+				if !strings.Contains(err.Error(), tc.errMsg) {
+					t.Errorf("got error '%s', want '%s", err, tc.errMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %q:", err)
+			}
+			if !reflect.DeepEqual(theme, tc.expected) {
+				t.Errorf("got theme %v, want %v", theme, tc.expected)
+			}
+		})
+	}
+}
+
+func TestThemeNames(t *testing.T) {
+	home, xdg := setup("./testdata/XDG", "./testdata/home")
+	defer reset(home, xdg)
+
+	expected := []string{"broken", "custom", "dracula", "gruvbox", "monochrome", "nord", "solarized-dark", "solarized-light"}
+
+	names, err := config.ThemeNames()
+	if err != nil {
+		t.Fatalf("unexpected error: %q:", err)
+	}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("got theme names %v, want %v", names, expected)
+	}
+}
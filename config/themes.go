@@ -0,0 +1,203 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// Directory within the configuration directory holding theme files
+	themesPath = "themes"
+	// Extension of theme files
+	themeExtension = ".theme"
+)
+
+// builtinThemes holds the themes bundled with gmifmt, by name.
+var builtinThemes = map[string]Config{
+	"nord": {
+		"header":       "#88c0d0 bold",
+		"header2":      "#81a1c1 bold",
+		"header3":      "#81a1c1",
+		"preformatted": "#ebcb8b",
+		"quoted":       "#a3be8c italic",
+		"quotebar":     "#4c566a",
+		"link":         "#5e81ac",
+		"linklabel":    "#88c0d0",
+		"linkurl":      "#4c566a",
+		"bullet":       "#b48ead",
+		"keyword":      "#81a1c1 bold",
+		"string":       "#a3be8c",
+		"comment":      "#616e88 italic",
+		"number":       "#b48ead",
+	},
+	"solarized-dark": {
+		"header":       "#268bd2 bold",
+		"header2":      "#2aa198 bold",
+		"header3":      "#2aa198",
+		"preformatted": "#b58900",
+		"quoted":       "#859900 italic",
+		"quotebar":     "#586e75",
+		"link":         "#6c71c4",
+		"linkurl":      "#586e75",
+		"bullet":       "#cb4b16",
+		"keyword":      "#859900",
+		"string":       "#2aa198",
+		"comment":      "#586e75 italic",
+		"number":       "#d33682",
+	},
+	"solarized-light": {
+		"header":       "#268bd2 bold",
+		"header2":      "#2aa198 bold",
+		"header3":      "#2aa198",
+		"preformatted": "#b58900",
+		"quoted":       "#859900 italic",
+		"quotebar":     "#93a1a1",
+		"link":         "#6c71c4",
+		"linkurl":      "#93a1a1",
+		"bullet":       "#cb4b16",
+		"keyword":      "#859900",
+		"string":       "#2aa198",
+		"comment":      "#93a1a1 italic",
+		"number":       "#d33682",
+	},
+	"gruvbox": {
+		"header":       "#fabd2f bold",
+		"header2":      "#fe8019 bold",
+		"header3":      "#fe8019",
+		"preformatted": "#b8bb26",
+		"quoted":       "#8ec07c italic",
+		"quotebar":     "#928374",
+		"link":         "#83a598",
+		"linkurl":      "#928374",
+		"bullet":       "#fb4934",
+		"keyword":      "#fb4934",
+		"string":       "#b8bb26",
+		"comment":      "#928374 italic",
+		"number":       "#d3869b",
+	},
+	"dracula": {
+		"header":       "#ff79c6 bold",
+		"header2":      "#bd93f9 bold",
+		"header3":      "#bd93f9",
+		"preformatted": "#f1fa8c",
+		"quoted":       "#50fa7b italic",
+		"quotebar":     "#6272a4",
+		"link":         "#8be9fd",
+		"linkurl":      "#6272a4",
+		"bullet":       "#ffb86c",
+		"keyword":      "#ff79c6",
+		"string":       "#f1fa8c",
+		"comment":      "#6272a4 italic",
+		"number":       "#bd93f9",
+	},
+	"monochrome": {
+		"header":       "bold underline",
+		"header2":      "bold",
+		"header3":      "bold",
+		"preformatted": "dim",
+		"quoted":       "italic",
+		"quotebar":     "dim",
+		"linklabel":    "underline",
+		"linkurl":      "dim",
+		"bullet":       "bold",
+		"keyword":      "bold",
+		"comment":      "italic",
+	},
+}
+
+// LoadTheme loads the named theme, a configuration of styles in the
+// same format as gmifmt configuration files. Themes are looked for as
+// files named after them in the following locations, in the listed
+// order, before falling back to the built-in themes:
+//
+//	$XDG_CONFIG_HOME/gemini/themes/<name>.theme
+//	$HOME/.config/gemini/themes/<name>.theme
+func LoadTheme(name string) (Config, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return nil, fmt.Errorf("invalid theme name '%s'", name)
+	}
+
+	dirs, err := themeDirs()
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range dirs {
+		f, err := os.Open(filepath.Join(dir, name+themeExtension))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		conf, err := load(f)
+		if err != nil {
+			return nil, fmt.Errorf("error loading theme '%s': %w", name, err)
+		}
+		return *conf, nil
+	}
+
+	builtin, ok := builtinThemes[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown theme '%s'", name)
+	}
+
+	// Copy the theme, so that callers may modify it
+	conf := make(Config, len(builtin))
+	for key, val := range builtin {
+		conf[key] = val
+	}
+	return conf, nil
+}
+
+// ThemeNames returns the names of the available themes, built-in or
+// found in the theme directories listed for LoadTheme, in
+// alphabetical order.
+func ThemeNames() ([]string, error) {
+	names := make(map[string]bool)
+	for name := range builtinThemes {
+		names[name] = true
+	}
+
+	dirs, err := themeDirs()
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range dirs {
+		files, err := filepath.Glob(filepath.Join(dir, "*"+themeExtension))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			names[strings.TrimSuffix(filepath.Base(file), themeExtension)] = true
+		}
+	}
+
+	var sorted []string
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	return sorted, nil
+}
+
+// themeDirs returns the directories searched for theme files, in
+// order.
+func themeDirs() ([]string, error) {
+	var dirs []string
+	if val, ok := os.LookupEnv("XDG_CONFIG_HOME"); ok {
+		dirs = append(dirs, filepath.Join(val, configPath, themesPath))
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+
+	return append(dirs, filepath.Join(home, ".config", configPath, themesPath)), nil
+}
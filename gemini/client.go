@@ -15,7 +15,8 @@ import (
 // option configures an aspect of the Gemini client.
 type option func(c *Client)
 
-// Timeout sets a socket connect timeout option on the client.
+// Timeout sets a timeout option on the client, covering the whole of
+// each request: connecting, the TLS handshake, and reading the response.
 func Timeout(timeout time.Duration) func(*Client) {
 	return func(c *Client) {
 		c.dialer.NetDialer.Timeout = timeout
@@ -29,11 +30,20 @@ func Config(config *tls.Config) func(*Client) {
 	}
 }
 
+// FollowRedirects sets whether the client follows redirect responses. If
+// not, redirects are returned to the caller like any other response.
+func FollowRedirects(follow bool) func(*Client) {
+	return func(c *Client) {
+		c.followRedirects = follow
+	}
+}
+
 // NewClient creates an instance of the Gemini client, configured as per the
 // option functions passed in.
 func NewClient(opts ...option) *Client {
 	c := &Client{
-		dialer: new(tls.Dialer),
+		dialer:          new(tls.Dialer),
+		followRedirects: true,
 	}
 	c.dialer.NetDialer = new(net.Dialer)
 
@@ -47,10 +57,15 @@ func NewClient(opts ...option) *Client {
 // Client is a Gemini client complete with configured
 // TLS dialer.
 type Client struct {
-	dialer *tls.Dialer
+	dialer          *tls.Dialer
+	followRedirects bool
 }
 
-// Get attempts to get the supplied Gemini URL.
+// Get attempts to get the supplied Gemini URL. If the server responds
+// with anything other than success, or a redirect the client does not
+// follow, Get returns the response along with an error describing it,
+// so callers can still inspect the response header. Redirects are
+// followed at most maxRedirects times.
 func (c *Client) Get(geminiURL string) (*Response, error) {
 	// If scheme missing, default to gemini://
 	if !strings.Contains(geminiURL, "://") {
@@ -67,9 +82,13 @@ func (c *Client) Get(geminiURL string) (*Response, error) {
 		return nil, fmt.Errorf("unsupported URL scheme '%s'", url.Scheme)
 	}
 
-	return c.get(*url)
+	return c.get(*url, 0)
 }
 
+// maxRedirects is the number of redirects the client follows for a
+// single request before giving up.
+const maxRedirects = 5
+
 // buildHostString takes the supplied URL and extracts the relevant
 // components for constructing a TCP host to connect to.
 func buildHostString(url url.URL) (string, error) {
@@ -81,22 +100,61 @@ func buildHostString(url url.URL) (string, error) {
 	return fmt.Sprintf("%s:%s", url.Hostname(), port), nil
 }
 
-// getConn connects to the given Gemini server and returns
-// the resulting net.Conn.
-func (c *Client) getConn(url url.URL) (net.Conn, error) {
+// connTimings records how long the stages of establishing a
+// connection took.
+type connTimings struct {
+	connect   time.Duration
+	handshake time.Duration
+}
+
+// getConn connects to the given Gemini server and returns the
+// resulting TLS connection. The TCP connection and TLS handshake
+// are made separately so each can be timed.
+func (c *Client) getConn(url url.URL) (*tls.Conn, connTimings, error) {
+	var timings connTimings
+
 	hostStr, err := buildHostString(url)
 	if err != nil {
-		return nil, err
+		return nil, timings, err
+	}
+
+	start := time.Now()
+	raw, err := c.dialer.NetDialer.Dial("tcp", hostStr)
+	if err != nil {
+		return nil, timings, err
 	}
+	timings.connect = time.Since(start)
 
 	// TODO: Check TLS certs here; keep in TOFU store a'la SSH's known_hosts
 
-	return c.dialer.Dial("tcp", hostStr)
+	config := &tls.Config{}
+	if c.dialer.Config != nil {
+		config = c.dialer.Config.Clone()
+	}
+	if config.ServerName == "" {
+		config.ServerName = url.Hostname()
+	}
+
+	// The timeout covers the handshake, request and response too, so a
+	// server that stops responding can't stall the client
+	if timeout := c.dialer.NetDialer.Timeout; timeout > 0 {
+		raw.SetDeadline(time.Now().Add(timeout))
+	}
+	conn := tls.Client(raw, config)
+	start = time.Now()
+	if err := conn.Handshake(); err != nil {
+		raw.Close()
+		return nil, timings, err
+	}
+	timings.handshake = time.Since(start)
+
+	return conn, timings, nil
 }
 
-// get makes the actual request over the internal net.Conn.
-func (c *Client) get(url url.URL) (*Response, error) {
-	conn, err := c.getConn(url)
+// get makes the actual request over the internal net.Conn. redirects
+// is the number of redirects already followed to reach url.
+func (c *Client) get(url url.URL, redirects int) (*Response, error) {
+	conn, timings, err := c.getConn(url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to '%s': %s", url.String(), err.Error())
 	}
@@ -117,18 +175,27 @@ func (c *Client) get(url url.URL) (*Response, error) {
 		return nil, fmt.Errorf("could not read response header: %w", err)
 	}
 
-	rsp := &Response{URL: url}
+	state := conn.ConnectionState()
+	rsp := &Response{
+		ConnectDuration:   timings.connect,
+		HandshakeDuration: timings.handshake,
+		URL:               url,
+		Header:            strings.TrimRight(rspHeader, "\r\n"),
+		TLS:               &state,
+	}
 	rsp.StatusCode, rsp.Meta, err = parseHeader(rspHeader)
 	if err != nil {
 		return nil, fmt.Errorf("could not parse response header: %w", err)
 	}
+	// Non-success responses have no body, so are complete here
+	rsp.ResponseDuration = time.Since(start)
 
 	switch rsp.StatusCode {
 	case StatusInput,
 		StatusSensitiveInput:
-		return nil, fmt.Errorf("unsupported feature")
+		return rsp, fmt.Errorf("unsupported feature")
 	case StatusSuccess:
-		body, err := processResponse(rsp.Meta, reader)
+		body, err := processResponse(reader)
 		if err != nil {
 			return nil, err
 		}
@@ -138,26 +205,40 @@ func (c *Client) get(url url.URL) (*Response, error) {
 		rsp.ResponseDuration = time.Since(start)
 	case StatusRedirectTemporary,
 		StatusRedirectPermanent:
+		if !c.followRedirects {
+			break
+		}
+		if redirects >= maxRedirects {
+			return rsp, fmt.Errorf("too many redirects, stopped after %d", maxRedirects)
+		}
 		url, err := url.Parse(rsp.Meta)
 		if err != nil {
 			return nil, fmt.Errorf("error parsing redirect URL: %w", err)
 		}
 
-		return c.get(*url)
+		return c.get(*url, redirects+1)
 	case StatusBadRequest:
-		return nil, fmt.Errorf("server could not process request: %s", rsp.Meta)
+		return rsp, fmt.Errorf("server could not process request: %s", rsp.Meta)
 	case StatusTemporaryFailure,
 		StatusServerUnavailable,
-		StatusPermanentFailure:
+		StatusCGIError,
+		StatusProxyError,
+		StatusSlowDown,
+		StatusPermanentFailure,
+		StatusNotFound,
+		StatusGone,
+		StatusProxyRequestRefused:
 		// TODO, temporary
-		return nil, fmt.Errorf("error: %s", rsp.Meta)
+		return rsp, fmt.Errorf("error: %s", rsp.Meta)
 	case StatusClientCertRequired:
 		// Can test with gemini://astrobotany.mozz.us/app
-		return nil, fmt.Errorf("resource requires a client certificate")
+		return rsp, fmt.Errorf("resource requires a client certificate")
 	case StatusCertNotAuthorised,
 		StatusCertNotValid:
 		// TODO, temporary
-		return nil, fmt.Errorf("certificate problem: %s", rsp.Meta)
+		return rsp, fmt.Errorf("certificate problem: %s", rsp.Meta)
+	default:
+		return rsp, fmt.Errorf("unexpected status %d: %s", rsp.StatusCode, rsp.Meta)
 	}
 
 	return rsp, nil
@@ -183,10 +264,9 @@ func parseHeader(header string) (int, string, error) {
 }
 
 // processResponse reads in a successful response, returning its body.
-func processResponse(meta string, reader io.Reader) ([]byte, error) {
-	if !strings.HasPrefix(meta, "text/gemini") {
-		return nil, fmt.Errorf("unsupported MIME type '%s'", meta)
-	}
+// Bodies of any MIME type are returned; callers can check it with the
+// response's MediaType method.
+func processResponse(reader io.Reader) ([]byte, error) {
 	body, err := io.ReadAll(reader)
 	if err != nil {
 		return nil, fmt.Errorf("error reading response body: %s", err.Error())
@@ -10,6 +10,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestParseHeader(t *testing.T) {
@@ -59,22 +60,19 @@ func TestParseHeader(t *testing.T) {
 
 func TestParseResponse(t *testing.T) {
 	testCases := map[string]struct {
-		meta     string
 		body     string
 		expected []byte
 		errMsg   string
 	}{
 		"valid response": {
-			meta:     "text/gemini",
 			body:     "# Valid gemtext",
 			expected: []byte("# Valid gemtext"),
 			errMsg:   "",
 		},
-		"invalid mime type": {
-			meta:     "text/invalid",
-			body:     "",
-			expected: nil,
-			errMsg:   "unsupported MIME type 'text/invalid'",
+		"non-gemtext response": {
+			body:     "\x89PNG\x00\xff",
+			expected: []byte("\x89PNG\x00\xff"),
+			errMsg:   "",
 		},
 	}
 
@@ -82,7 +80,7 @@ func TestParseResponse(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			var buf bytes.Buffer
 			buf.WriteString(tc.body)
-			b, err := processResponse(tc.meta, bufio.NewReader(&buf))
+			b, err := processResponse(bufio.NewReader(&buf))
 			if tc.errMsg != "" {
 				if err == nil {
 					t.Error("expected an error, but got nil")
@@ -197,6 +195,9 @@ func (s *Server) run() {
 		"gemini://localhost:11965/failure-temporary":              "./testdata/FailureTemporary",
 		"gemini://localhost:11965/failure-permanent":              "./testdata/FailurePermanent",
 		"gemini://localhost:11965/invalid-header":                 "./testdata/InvalidHeader",
+		"gemini://localhost:11965/not-found":                      "./testdata/NotFound",
+		"gemini://localhost:11965/unknown-status":                 "./testdata/UnknownStatus",
+		"gemini://localhost:11965/redirect-loop":                  "./testdata/RedirectLoop",
 		"gemini://localhost:11965/redirected-temporarily-to-this": "./testdata/RedirectedTemporarilyToThis",
 		"gemini://localhost:11965/redirected-permanently-to-this": "./testdata/RedirectedPermanentlyToThis",
 	}
@@ -332,6 +333,22 @@ Followed by some body text
 			expectedBody:   "",
 			expectedErr:    "error: permanent failure",
 		},
+		"not found": {
+			testURL:        fmt.Sprintf("gemini://%s/not-found", svr.URL),
+			expectedStatus: StatusNotFound,
+			expectedMeta:   "not found",
+			expectedErr:    "error: not found",
+		},
+		"unknown status": {
+			testURL:        fmt.Sprintf("gemini://%s/unknown-status", svr.URL),
+			expectedStatus: 70,
+			expectedMeta:   "unknown",
+			expectedErr:    "unexpected status 70: unknown",
+		},
+		"redirect loop": {
+			testURL:     fmt.Sprintf("gemini://%s/redirect-loop", svr.URL),
+			expectedErr: "too many redirects",
+		},
 	}
 
 	client := DefaultClient
@@ -368,3 +385,183 @@ Followed by some body text
 		})
 	}
 }
+
+func TestGetNoFollow(t *testing.T) {
+	svr, err := NewServer()
+	if err != nil {
+		t.Fatal("unable to start test server:", err)
+	}
+	defer svr.Close()
+
+	testCases := map[string]struct {
+		testURL        string
+		expectedStatus int
+		expectedHeader string
+		expectedErr    string
+	}{
+		"temporary redirect": {
+			testURL:        fmt.Sprintf("gemini://%s/redirect-temporary", svr.URL),
+			expectedStatus: StatusRedirectTemporary,
+			expectedHeader: "30 gemini://localhost:11965/redirected-temporarily-to-this",
+		},
+		"permanent redirect": {
+			testURL:        fmt.Sprintf("gemini://%s/redirect-permanent", svr.URL),
+			expectedStatus: StatusRedirectPermanent,
+			expectedHeader: "31 gemini://localhost:11965/redirected-permanently-to-this",
+		},
+		"success": {
+			testURL:        fmt.Sprintf("gemini://%s/success", svr.URL),
+			expectedStatus: StatusSuccess,
+			expectedHeader: "20 text/gemini",
+		},
+		"failure permanent": {
+			testURL:        fmt.Sprintf("gemini://%s/failure-permanent", svr.URL),
+			expectedStatus: StatusPermanentFailure,
+			expectedHeader: "50 permanent failure",
+			expectedErr:    "error: permanent failure",
+		},
+	}
+
+	client := NewClient(
+		Config(&tls.Config{InsecureSkipVerify: true}),
+		FollowRedirects(false),
+	)
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			rsp, err := client.Get(tc.testURL)
+
+			if tc.expectedErr != "" {
+				if err == nil {
+					t.Errorf("expected error '%s', got nil", tc.expectedErr)
+				} else if !strings.Contains(err.Error(), tc.expectedErr) {
+					t.Errorf("got error of '%s', want '%s'",
+						err, tc.expectedErr)
+				}
+			} else if err != nil {
+				t.Errorf("unexpected error: %q:", err)
+				return
+			}
+			if rsp == nil {
+				t.Fatal("expected a response, got nil")
+			}
+			if rsp.StatusCode != tc.expectedStatus {
+				t.Errorf("got status code of %d, want %d",
+					rsp.StatusCode, tc.expectedStatus)
+			}
+			if rsp.Header != tc.expectedHeader {
+				t.Errorf("got header of '%s', want '%s'",
+					rsp.Header, tc.expectedHeader)
+			}
+			if rsp.TLS == nil {
+				t.Error("expected TLS connection state, got nil")
+			}
+			if rsp.HandshakeDuration == 0 {
+				t.Error("expected TLS handshake duration to be recorded")
+			}
+		})
+	}
+}
+
+func TestMediaType(t *testing.T) {
+	testCases := map[string]struct {
+		status       int
+		meta         string
+		expectedType string
+		expectedLang string
+		errMsg       string
+	}{
+		"gemtext": {
+			status:       StatusSuccess,
+			meta:         "text/gemini",
+			expectedType: "text/gemini",
+		},
+		"with parameters": {
+			status:       StatusSuccess,
+			meta:         "text/gemini; charset=utf-8; lang=en",
+			expectedType: "text/gemini",
+			expectedLang: "en",
+		},
+		"empty meta": {
+			status:       StatusSuccess,
+			expectedType: "text/gemini",
+		},
+		"not successful": {
+			status: StatusNotFound,
+			meta:   "not found",
+			errMsg: "no media type for status 51",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			rsp := Response{StatusCode: tc.status, Meta: tc.meta}
+			mediaType, params, err := rsp.MediaType()
+			if tc.errMsg != "" {
+				if err == nil {
+					t.Error("expected an error, but got nil")
+				} else if !strings.Contains(err.Error(), tc.errMsg) {
+					t.Errorf("got error '%s', want '%s", err, tc.errMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %q:", err)
+				return
+			}
+			if mediaType != tc.expectedType {
+				t.Errorf("got media type of '%s', want '%s'", mediaType, tc.expectedType)
+			}
+			if params["lang"] != tc.expectedLang {
+				t.Errorf("got lang of '%s', want '%s'", params["lang"], tc.expectedLang)
+			}
+		})
+	}
+}
+
+func TestGetTimeout(t *testing.T) {
+	cert, err := tls.LoadX509KeyPair(serverCert, serverKey)
+	if err != nil {
+		t.Fatal("could not load TLS certs:", err)
+	}
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		t.Fatal("unable to start test server:", err)
+	}
+	defer l.Close()
+
+	// The server completes the handshake and reads the request, but
+	// never responds
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		bufio.NewReader(conn).ReadString('\n')
+		<-done
+	}()
+
+	client := NewClient(
+		Timeout(200*time.Millisecond),
+		Config(&tls.Config{InsecureSkipVerify: true}),
+	)
+	result := make(chan error, 1)
+	go func() {
+		_, err := client.Get(fmt.Sprintf("gemini://%s/", l.Addr()))
+		result <- err
+	}()
+
+	select {
+	case err := <-result:
+		if err == nil {
+			t.Fatal("expected a timeout error, got nil")
+		}
+		if !strings.Contains(err.Error(), "timeout") {
+			t.Errorf("got error '%s', want a timeout", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("request did not time out")
+	}
+}
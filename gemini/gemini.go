@@ -15,6 +15,7 @@ package gemini
 import (
 	"crypto/tls"
 	"fmt"
+	"mime"
 	"net/url"
 	"time"
 )
@@ -92,12 +93,22 @@ type Response struct {
 	// to receive a response
 	ResponseDuration time.Duration
 
+	// ConnectDuration and HandshakeDuration record the time
+	// it took to connect to the server, and to complete the
+	// TLS handshake once connected
+	ConnectDuration   time.Duration
+	HandshakeDuration time.Duration
+
 	// URL is the URL used to obtain this response.
 	URL url.URL
 
 	// StatusCode is the response status code.
 	StatusCode int
 
+	// Header is the raw response header line as sent by the
+	// server, minus its trailing CRLF.
+	Header string
+
 	// Meta holds any response header meta values; these
 	// vary depending on status code.
 	Meta string
@@ -109,6 +120,27 @@ type Response struct {
 	// Body is an array of bytes holding the received
 	// page content.
 	Body []byte
+
+	// TLS holds details of the TLS connection the response
+	// was received over.
+	TLS *tls.ConnectionState
+}
+
+// MediaType parses the MIME type held in the Meta value of a successful
+// response, returning the media type and any parameters (e.g. charset,
+// lang). It returns an error if the response was not successful or
+// its Meta value is not a valid MIME type.
+func (r *Response) MediaType() (string, map[string]string, error) {
+	if r.StatusCode != StatusSuccess {
+		return "", nil, fmt.Errorf("no media type for status %s", Status(r.StatusCode))
+	}
+	// Servers may omit the MIME type, in which case the spec
+	// says to assume gemtext
+	if r.Meta == "" {
+		return MIMEType, map[string]string{}, nil
+	}
+
+	return mime.ParseMediaType(r.Meta)
 }
 
 // DefaultClient is a barebones client, used for basic Gemini calls. It
@@ -0,0 +1,121 @@
+/*
+Package geminitest provides a Gemini server for testing Gemini clients,
+in the manner of net/http/httptest.
+*/
+package geminitest
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Server is a Gemini server listening on the loopback interface, which
+// responds to requests with canned responses.
+type Server struct {
+	// Base URL of the server, with no trailing slash
+	URL string
+
+	listener  net.Listener
+	responses map[string]string
+}
+
+// NewServer starts a server responding to requests for the paths in
+// responses with the raw responses, header and body, mapped to them.
+// Requests for other paths get a 51 response. The server presents a
+// self-signed certificate, so clients must skip verifying it.
+func NewServer(responses map[string]string) (*Server, error) {
+	cert, err := selfSignedCert()
+	if err != nil {
+		return nil, fmt.Errorf("could not create TLS cert: %w", err)
+	}
+
+	conf := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+	}
+	l, err := tls.Listen("tcp", "127.0.0.1:0", conf)
+	if err != nil {
+		return nil, fmt.Errorf("could not create test server: %w", err)
+	}
+
+	s := &Server{
+		URL:       "gemini://" + l.Addr().String(),
+		listener:  l,
+		responses: responses,
+	}
+	go s.run()
+
+	return s, nil
+}
+
+// Close shuts the server down.
+func (s *Server) Close() {
+	s.listener.Close()
+}
+
+// run responds to connections until the server is closed.
+func (s *Server) run() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go s.respond(conn)
+	}
+}
+
+// respond reads a request from the connection and writes its response.
+func (s *Server) respond(conn net.Conn) {
+	defer conn.Close()
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return
+	}
+	u, err := url.Parse(strings.TrimRight(line, "\r\n"))
+	if err != nil {
+		fmt.Fprint(conn, "59 bad request\r\n")
+		return
+	}
+
+	rsp, ok := s.responses[u.Path]
+	if !ok {
+		rsp = "51 not found\r\n"
+	}
+	fmt.Fprint(conn, rsp)
+}
+
+// selfSignedCert returns a short-lived certificate for the loopback
+// interface, signed by its own key.
+func selfSignedCert() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
@@ -15,6 +15,9 @@ import (
 */
 
 // Close is the reset code for 16m ansi color codes.
+//
+// Deprecated: Close resets only the foreground colour, leaving any
+// other text attributes and the background in place. Use Reset.
 const Close = "\u001B[39m"
 
 func isHexDigit(c byte) bool {
@@ -92,9 +95,12 @@ func NewColour(hex string) *Colour {
 	return &Colour{red: r, green: g, blue: b}
 }
 
-// WriteStringAnsi16m writes the string used to set a 24bit foreground color.
-// WrapInAnsi16mColour wraps the suppled str in Ansi 16m escape codes for the
-// supplied colour.
+// WriteAnsi16mColour writes the escape code setting the foreground to
+// the supplied colour, in 24-bit colour.
+//
+// Deprecated: the escape code is always 24-bit, whatever the terminal's
+// colour depth, and sets only the foreground. Use a Style, which
+// renderers output at the depth set by ColourDepth.
 func WriteAnsi16mColour(out *strings.Builder, colour Colour) {
 	out.WriteString("\u001B[38;2;")
 	out.WriteString(strconv.Itoa(int(colour.red)))
@@ -0,0 +1,336 @@
+package gemtext
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+)
+
+// Line is a single, typed line of a gemtext document. Calling String
+// on a Line serialises it back to gemtext.
+//
+// Lines obtained by parsing keep their source text, so they serialise
+// back exactly as they were written as long as they're not modified.
+type Line interface {
+	String() string
+}
+
+// Text is a plain text line.
+type Text struct {
+	Text string
+}
+
+func (t Text) String() string {
+	return t.Text
+}
+
+// Link is a link line, comprised of a URL and an optional
+// user-friendly label.
+type Link struct {
+	URL   string
+	Label string
+
+	raw string
+}
+
+func (l Link) String() string {
+	if l.raw != "" {
+		if url, label := splitLink(l.raw[len(link.prefix):]); url == l.URL && label == l.Label {
+			return l.raw
+		}
+	}
+
+	if l.Label == "" {
+		return link.prefix + " " + l.URL
+	}
+	return link.prefix + " " + l.URL + " " + l.Label
+}
+
+// Heading is a heading line, of level 1 to 3.
+type Heading struct {
+	Level int
+	Text  string
+
+	raw string
+}
+
+func (h Heading) String() string {
+	if h.raw != "" {
+		if parsed := parseHeading(h.raw); parsed.Level == h.Level && parsed.Text == h.Text {
+			return h.raw
+		}
+	}
+
+	return strings.Repeat(header.prefix, h.Level) + " " + h.Text
+}
+
+// ListItem is an unordered list item line.
+type ListItem struct {
+	Text string
+
+	raw string
+}
+
+func (li ListItem) String() string {
+	if li.raw != "" && parseListItem(li.raw).Text == li.Text {
+		return li.raw
+	}
+
+	return listItem.prefix + li.Text
+}
+
+// Quote is a quote line.
+type Quote struct {
+	Text string
+}
+
+func (q Quote) String() string {
+	return quoted.prefix + q.Text
+}
+
+// Preformatted is a block of preformatted lines, including the toggle
+// lines surrounding it. Alt holds any alt text given on the opening
+// toggle line.
+type Preformatted struct {
+	Alt   string
+	Lines []string
+
+	// Source text of the toggle lines
+	open, close string
+	// Set if the document ended before the block was closed
+	unterminated bool
+}
+
+func (p Preformatted) String() string {
+	open := preformattedToggle.prefix + p.Alt
+	if p.open != "" && parseAlt(p.open) == p.Alt {
+		open = p.open
+	}
+
+	lines := append([]string{open}, p.Lines...)
+	if !p.unterminated {
+		close := preformattedToggle.prefix
+		if p.close != "" {
+			close = p.close
+		}
+		lines = append(lines, close)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Document is a parsed gemtext document.
+type Document struct {
+	Lines []Line
+
+	// CRLF is set if the document's lines are terminated with CRLF
+	// rather than LF. Documents with mixed line endings are
+	// serialised using the ending of their first line.
+	CRLF bool
+
+	// Set if the document's last line has no line terminator
+	noFinalNewline bool
+}
+
+// String serialises the document back to gemtext.
+func (d *Document) String() string {
+	var b strings.Builder
+	d.WriteTo(&b)
+
+	return b.String()
+}
+
+// WriteTo serialises the document back to gemtext, writing it to
+// the supplied writer.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	eol := "\n"
+	if d.CRLF {
+		eol = "\r\n"
+	}
+
+	var written int64
+	for i, line := range d.Lines {
+		s := line.String()
+		if d.CRLF {
+			s = strings.ReplaceAll(s, "\n", eol)
+		}
+		if i < len(d.Lines)-1 || !d.noFinalNewline {
+			s += eol
+		}
+
+		n, err := io.WriteString(w, s)
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+
+	return written, nil
+}
+
+// Parser reads typed lines from a gemtext document one at a time,
+// so large documents can be processed without holding them in memory.
+type Parser struct {
+	r *bufio.Reader
+	// Details of the line endings seen so far
+	lines int
+	crlf  bool
+	eof   bool
+	noEOL bool
+}
+
+// NewParser returns a Parser reading gemtext from the supplied reader.
+func NewParser(r io.Reader) *Parser {
+	return &Parser{r: bufio.NewReader(r)}
+}
+
+// Next returns the next line in the document. Preformatted blocks are
+// returned as a single Line once complete. Next returns io.EOF once
+// there are no more lines.
+func (p *Parser) Next() (Line, error) {
+	line, err := p.readLine()
+	if err != nil {
+		return nil, err
+	}
+
+	lineType := getLineType(false, line)
+	switch lineType {
+	case preformattedToggle:
+		return p.readPreformatted(line)
+	case link:
+		url, label := splitLink(line[len(link.prefix):])
+		return Link{URL: url, Label: label, raw: line}, nil
+	case header, header2, header3:
+		return parseHeading(line), nil
+	case listItem:
+		return parseListItem(line), nil
+	case quoted:
+		return Quote{Text: line[len(quoted.prefix):]}, nil
+	default:
+		return Text{Text: line}, nil
+	}
+}
+
+// readPreformatted reads the lines of a preformatted block, up to and
+// including its closing toggle line.
+func (p *Parser) readPreformatted(open string) (Line, error) {
+	pre := Preformatted{Alt: parseAlt(open), open: open}
+
+	for {
+		line, err := p.readLine()
+		if errors.Is(err, io.EOF) {
+			pre.unterminated = true
+			return pre, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if getLineType(true, line) == preformattedToggle {
+			pre.close = line
+			return pre, nil
+		}
+		pre.Lines = append(pre.Lines, line)
+	}
+}
+
+// readLine reads a single line, stripping its line terminator.
+func (p *Parser) readLine() (string, error) {
+	if p.eof {
+		return "", io.EOF
+	}
+
+	line, err := p.r.ReadString('\n')
+	if errors.Is(err, io.EOF) {
+		p.eof = true
+		if line == "" {
+			return "", io.EOF
+		}
+		p.noEOL = true
+	} else if err != nil {
+		return "", err
+	}
+
+	p.lines++
+	if strings.HasSuffix(line, "\r\n") {
+		if p.lines == 1 {
+			p.crlf = true
+		}
+		return line[:len(line)-2], nil
+	}
+
+	return strings.TrimSuffix(line, "\n"), nil
+}
+
+// Parse reads and parses a complete gemtext document from the
+// supplied reader.
+func Parse(r io.Reader) (*Document, error) {
+	p := NewParser(r)
+	doc := &Document{}
+
+	for {
+		line, err := p.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		doc.Lines = append(doc.Lines, line)
+	}
+	doc.CRLF = p.crlf
+	doc.noFinalNewline = p.noEOL
+
+	return doc, nil
+}
+
+// parseHeading parses the supplied heading line.
+func parseHeading(line string) Heading {
+	level := 1
+	for level < 3 && strings.HasPrefix(line[level:], header.prefix) {
+		level++
+	}
+
+	return Heading{
+		Level: level,
+		Text:  strings.TrimLeft(line[level:], " \t"),
+		raw:   line,
+	}
+}
+
+// parseListItem parses the supplied list item line.
+func parseListItem(line string) ListItem {
+	return ListItem{Text: line[len(listItem.prefix):], raw: line}
+}
+
+// parseAlt returns the alt text given on a preformatted toggle line.
+func parseAlt(line string) string {
+	return strings.TrimSpace(line[len(preformattedToggle.prefix):])
+}
+
+// escapeLine returns the supplied line of converted text with a space
+// prefixed to any of its text that would otherwise be parsed back as a
+// different type of line once serialised: text lines starting with the
+// prefix of another line type, and preformatted lines starting with a
+// toggle, which would end their block early.
+func escapeLine(l Line) Line {
+	switch l := l.(type) {
+	case Text:
+		if getLineType(false, l.Text) != text {
+			l.Text = " " + l.Text
+		}
+		return l
+	case Preformatted:
+		lines := make([]string, len(l.Lines))
+		for i, line := range l.Lines {
+			if getLineType(true, line) == preformattedToggle {
+				line = " " + line
+			}
+			lines[i] = line
+		}
+		l.Lines = lines
+		return l
+	}
+	return l
+}
@@ -0,0 +1,189 @@
+package gemtext
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// withoutSource strips the retained source text from a parsed line,
+// so it can be compared with a constructed one.
+func withoutSource(l Line) Line {
+	switch l := l.(type) {
+	case Link:
+		l.raw = ""
+		return l
+	case Heading:
+		l.raw = ""
+		return l
+	case ListItem:
+		l.raw = ""
+		return l
+	case Preformatted:
+		l.open, l.close = "", ""
+		return l
+	}
+	return l
+}
+
+func TestParse(t *testing.T) {
+	testCases := map[string]struct {
+		input    string
+		expected []Line
+	}{
+		"text": {
+			input:    "Just a regular text line\n\n",
+			expected: []Line{Text{Text: "Just a regular text line"}, Text{}},
+		},
+		"link with label": {
+			input:    "=>  gemini://some.url/\t Some URL\n",
+			expected: []Line{Link{URL: "gemini://some.url/", Label: "Some URL"}},
+		},
+		"link without label": {
+			input:    "=>gemini://some.url/\n",
+			expected: []Line{Link{URL: "gemini://some.url/"}},
+		},
+		"headings": {
+			input: "# One\n##Two\n###  Three\n",
+			expected: []Line{
+				Heading{Level: 1, Text: "One"},
+				Heading{Level: 2, Text: "Two"},
+				Heading{Level: 3, Text: "Three"},
+			},
+		},
+		"hashtag": {
+			input:    "#hashtag\n",
+			expected: []Line{Heading{Level: 1, Text: "hashtag"}},
+		},
+		"list item": {
+			input:    "* Bullet item\n",
+			expected: []Line{ListItem{Text: "Bullet item"}},
+		},
+		"quote": {
+			input:    "> A quote\n",
+			expected: []Line{Quote{Text: " A quote"}},
+		},
+		"preformatted with alt text": {
+			input: "```go\nfunc main() {\n=> not a link\n}\n```\n",
+			expected: []Line{
+				Preformatted{Alt: "go", Lines: []string{"func main() {", "=> not a link", "}"}},
+			},
+		},
+		"unterminated preformatted": {
+			input: "```\nsome art",
+			expected: []Line{
+				Preformatted{Lines: []string{"some art"}, unterminated: true},
+			},
+		},
+		"spec link examples": {
+			input: "=> gemini://example.org/\n" +
+				"=> gemini://example.org/ An example link\n" +
+				"=> gemini://example.org/foo\tAnother example link at the same host\n" +
+				"=> foo/bar/baz.txt\tA relative link\n" +
+				"=> \tgopher://example.org:70/1 A gopher link\n",
+			expected: []Line{
+				Link{URL: "gemini://example.org/"},
+				Link{URL: "gemini://example.org/", Label: "An example link"},
+				Link{URL: "gemini://example.org/foo", Label: "Another example link at the same host"},
+				Link{URL: "foo/bar/baz.txt", Label: "A relative link"},
+				Link{URL: "gopher://example.org:70/1", Label: "A gopher link"},
+			},
+		},
+		"list items need a space": {
+			input: "* Mercury\n*emphasis*\n*\n",
+			expected: []Line{
+				ListItem{Text: "Mercury"},
+				Text{Text: "*emphasis*"},
+				Text{Text: "*"},
+			},
+		},
+		"crlf": {
+			input:    "# Heading\r\ntext\r\n",
+			expected: []Line{Heading{Level: 1, Text: "Heading"}, Text{Text: "text"}},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			doc, err := Parse(strings.NewReader(tc.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+			if len(doc.Lines) != len(tc.expected) {
+				t.Fatalf("got %d lines, want %d", len(doc.Lines), len(tc.expected))
+			}
+			for i, line := range doc.Lines {
+				if got := withoutSource(line); !reflect.DeepEqual(got, tc.expected[i]) {
+					t.Errorf("line %d does not match; got %#v, want %#v", i, got, tc.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	testCases := map[string]string{
+		"empty":               "",
+		"no final newline":    "# Heading\ntext",
+		"blank lines":         "\n\n\n",
+		"link whitespace":     "=>\tgemini://some.url/   \tSome  URL  \n=>/relative\n",
+		"heading whitespace":  "#Heading\n##   Heading 2\n### \n",
+		"list item":           "* item\n*  spaced item\n",
+		"preformatted toggle": "``` alt text \nart\n``` trailing\n",
+		"unterminated":        "```\nart\n",
+		"crlf":                "# Heading\r\n```\r\nart\r\n```\r\n",
+	}
+
+	// The test documents used elsewhere should round-trip too
+	paths, err := filepath.Glob(filepath.Join("testdata", "*.input"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testCases[path] = string(b)
+	}
+
+	for name, input := range testCases {
+		t.Run(name, func(t *testing.T) {
+			doc, err := Parse(strings.NewReader(input))
+			if err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+			if got := doc.String(); got != input {
+				t.Errorf("got '%q', want '%q'", got, input)
+			}
+		})
+	}
+}
+
+func TestLineString(t *testing.T) {
+	testCases := map[string]struct {
+		line     Line
+		expected string
+	}{
+		"link":               {line: Link{URL: "gemini://some.url/", Label: "Some URL"}, expected: "=> gemini://some.url/ Some URL"},
+		"link without label": {line: Link{URL: "/relative"}, expected: "=> /relative"},
+		"heading":            {line: Heading{Level: 2, Text: "Heading"}, expected: "## Heading"},
+		"list item":          {line: ListItem{Text: "item"}, expected: "* item"},
+		"quote":              {line: Quote{Text: "quote"}, expected: ">quote"},
+		"preformatted":       {line: Preformatted{Alt: "sh", Lines: []string{"ls -l"}}, expected: "```sh\nls -l\n```"},
+		"modified parsed link": {
+			line:     Link{URL: "gemini://other.url/", Label: "Label", raw: "=>  gemini://some.url/   Label"},
+			expected: "=> gemini://other.url/ Label",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if got := tc.line.String(); got != tc.expected {
+				t.Errorf("got '%s', want '%s'", got, tc.expected)
+			}
+		})
+	}
+}
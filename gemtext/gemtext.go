@@ -1,54 +1,70 @@
 package gemtext
 
 import (
-	"bufio"
 	"fmt"
 	"io"
+	"net/url"
 	"strings"
 
-	"github.com/chriswalker/gmi-utils/config"
+	"github.com/chriswalker/gmi-utils/terminal"
 )
 
 // lineType represents the various lines types in Gemtext.
 type lineType struct {
-	// The line type's prefix
+	// The line type's name, as used in configuration
+	name string
+	// The line type's prefix, as defined by the spec
 	prefix string
-	// Output colour; if nil no colour assigned
-	Colour *Colour
+	// The marker output before the line's text, if any
+	marker string
 }
 
 var (
-	text               = lineType{prefix: ""}
-	link               = lineType{prefix: "=>"}
-	preformattedToggle = lineType{prefix: "```"}
-	preformatted       = lineType{prefix: ""}
-	header             = lineType{prefix: "#"}
-	header2            = lineType{prefix: "##"}
-	header3            = lineType{prefix: "###"}
-	listItem           = lineType{prefix: "*"}
-	quoted             = lineType{prefix: ">"}
+	text               = lineType{name: "text", prefix: ""}
+	link               = lineType{name: "link", prefix: "=>"}
+	preformattedToggle = lineType{name: "preformattedToggle", prefix: "```"}
+	preformatted       = lineType{name: "preformatted", prefix: ""}
+	header             = lineType{name: "header", prefix: "#", marker: "#"}
+	header2            = lineType{name: "header2", prefix: "##", marker: "##"}
+	header3            = lineType{name: "header3", prefix: "###", marker: "###"}
+	listItem           = lineType{name: "listItem", prefix: "* ", marker: "*"}
+	quoted             = lineType{name: "quoted", prefix: ">", marker: ">"}
 )
 
-// Configure takes any colour values set in the given
-// Config struct and applies them to the defined line types.
-func Configure(conf config.Config) {
-	if val, ok := conf["preformatted"]; ok {
-		preformatted.Colour = NewColour(val)
-	}
-	if val, ok := conf["header"]; ok {
-		header.Colour = NewColour(val)
-	}
-	if val, ok := conf["header2"]; ok {
-		header2.Colour = NewColour(val)
-	}
-	if val, ok := conf["header3"]; ok {
-		header3.Colour = NewColour(val)
-	}
-	if val, ok := conf["quoted"]; ok {
-		quoted.Colour = NewColour(val)
+// outputOptions holds optional settings for Output.
+type outputOptions struct {
+	wrapOptions
+	altText      bool
+	maxWidth     int
+	numberLinks  bool
+	references   References
+	base         *url.URL
+	markExternal bool
+	fullPage     bool
+	css          string
+	manSection   string
+	theme        Theme
+	depth        terminal.ColourDepth
+	highlight    bool
+}
+
+// Option configures an aspect of Output's formatting.
+type Option func(o *outputOptions)
+
+// AltText sets whether the alt text of preformatted blocks is output
+// as a caption before the block.
+func AltText(show bool) Option {
+	return func(o *outputOptions) {
+		o.altText = show
 	}
-	if val, ok := conf["link"]; ok {
-		link.Colour = NewColour(val)
+}
+
+// MaxWidth sets the maximum width of the output text, in columns. If
+// the terminal is wider than this plus margins, the text is centred
+// within it. A width of zero uses the full terminal width.
+func MaxWidth(columns int) Option {
+	return func(o *outputOptions) {
+		o.maxWidth = columns
 	}
 }
 
@@ -56,23 +72,161 @@ func Configure(conf config.Config) {
 // the supplied writer (usually os.Stdout). The caller
 // provides the current width of the terminal, which is
 // used to determine text wrapping and margins.
-func Output(width, margin int, r io.Reader, w io.Writer) {
-	preformatted := false
-	var block block
-
-	s := bufio.NewScanner(r)
-	for s.Scan() {
-		block = NewBlock(width, margin, preformatted, s.Text())
-		if block.lineType == preformattedToggle {
-			preformatted = !preformatted
-			continue
+func Output(width, margin int, r io.Reader, w io.Writer, opts ...Option) {
+	Render(r, NewTerminalRenderer(w, width, margin, opts...))
+}
+
+// terminalRenderer renders gemtext for display in a terminal, wrapped
+// to its width and coloured as configured.
+type terminalRenderer struct {
+	w             io.Writer
+	width, margin int
+	o             outputOptions
+	refs          linkRefs
+}
+
+// NewTerminalRenderer returns a Renderer formatting gemtext for display
+// in a terminal of the supplied width, with margins either side.
+func NewTerminalRenderer(w io.Writer, width, margin int, opts ...Option) Renderer {
+	return newTerminalRenderer(w, width, margin, opts...)
+}
+
+// newTerminalRenderer creates a terminal renderer as per
+// NewTerminalRenderer.
+func newTerminalRenderer(w io.Writer, width, margin int, opts ...Option) *terminalRenderer {
+	t := &terminalRenderer{w: w, width: width, margin: margin}
+	t.o.depth = terminal.TrueColour
+	t.o.theme = defaultTheme
+	for _, opt := range opts {
+		opt(&t.o)
+	}
+
+	// Widen the margins to centre text narrower than the terminal
+	if t.o.maxWidth > 0 && width-margin*2 > t.o.maxWidth {
+		t.margin = (width - t.o.maxWidth) / 2
+	}
+
+	return t
+}
+
+func (t *terminalRenderer) Start() error {
+	t.refs = linkRefs{}
+	return nil
+}
+
+func (t *terminalRenderer) Text(line Text) error {
+	return t.write(t.o.wrapBlock(text, t.width, t.margin, line.Text))
+}
+
+func (t *terminalRenderer) Link(l Link) error {
+	return t.write(block{
+		lineType: link,
+		lines:    []string{t.o.link(&t.refs, l.URL, l.Label)},
+	})
+}
+
+func (t *terminalRenderer) Heading(h Heading) error {
+	// Headings are usually preceded by a blank line, so follow the
+	// section's references with one too
+	if t.o.references == ReferencesSection {
+		if refsBlock := t.refs.flush(); len(refsBlock.lines) > 0 {
+			if err := t.write(refsBlock); err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintln(t.w); err != nil {
+				return err
+			}
 		}
-		fmt.Fprint(w, block.String(margin))
 	}
+
+	lineType := header
+	switch h.Level {
+	case 2:
+		lineType = header2
+	case 3:
+		lineType = header3
+	}
+	return t.write(t.o.wrapBlock(lineType, t.width, t.margin, h.Text))
 }
 
-// getLineType returns the lineType for the supplied based on its
-// prefix value.
+func (t *terminalRenderer) ListItem(li ListItem) error {
+	return t.write(t.o.wrapBlock(listItem, t.width, t.margin, li.Text))
+}
+
+func (t *terminalRenderer) Quote(q Quote) error {
+	return t.write(t.o.wrapBlock(quoted, t.width, t.margin, q.Text))
+}
+
+func (t *terminalRenderer) Preformatted(p Preformatted) error {
+	if t.o.altText {
+		if _, err := io.WriteString(t.w, caption(t.o, t.width, t.margin, p.Alt)); err != nil {
+			return err
+		}
+	}
+
+	lines := p.Lines
+	if lang, ok := languageOf(p.Alt); ok && t.o.highlight {
+		lines = t.o.highlightLines(lang, lines)
+	}
+	return t.write(block{lineType: preformatted, lines: lines})
+}
+
+func (t *terminalRenderer) End() error {
+	refsBlock := t.refs.flush()
+	if t.o.references == ReferencesNone || len(refsBlock.lines) == 0 {
+		return nil
+	}
+
+	if _, err := fmt.Fprintln(t.w); err != nil {
+		return err
+	}
+	return t.write(refsBlock)
+}
+
+// write outputs the supplied block.
+func (t *terminalRenderer) write(b block) error {
+	_, err := io.WriteString(t.w, t.o.blockString(b, t.margin))
+	return err
+}
+
+// blockString outputs the lines of the supplied block as per
+// block.String, in the style the theme gives its line type.
+func (o outputOptions) blockString(b block, margin int) string {
+	b.style = o.theme.style(b.lineType).escape(o.depth)
+	if marker, ok := o.theme.markerStyle(b.lineType); ok {
+		b.markerStyle = marker.escape(o.depth)
+	}
+
+	return b.String(margin)
+}
+
+// caption formats the alt text of a preformatted block for output
+// before the block. It returns an empty string if there's no alt text.
+func caption(o outputOptions, width, margin int, alt string) string {
+	if alt == "" {
+		return ""
+	}
+
+	b := block{lineType: preformatted}
+	for _, line := range o.wrap(width-margin*2-2, alt) {
+		b.lines = append(b.lines, fmt.Sprintf("[%s]", line))
+	}
+
+	return o.blockString(b, margin)
+}
+
+// getLineType returns the lineType for the supplied line based on its
+// prefix value. Classification follows the gemtext specification:
+//
+// - Lines starting with "```" toggle preformatted mode; while in it,
+//   all other lines are preformatted text
+// - Lines starting with "=>" are links
+// - Lines starting with "#", "##" or "###" are headings; any whitespace
+//   between the "#"s and the heading text is optional, so a line such
+//   as "#hashtag" is a heading too, as the spec requires
+// - Lines starting with "* " (note the space) are list items
+// - Lines starting with ">" are quotes
+// - Everything else is text
 func getLineType(isPreformatted bool, line string) lineType {
 	switch {
 	case strings.HasPrefix(line, preformattedToggle.prefix):
@@ -109,6 +263,9 @@ func getLineType(isPreformatted bool, line string) lineType {
 type block struct {
 	// The type of line this block represents
 	lineType lineType
+	// Escape sequences styling the block's lines, and their markers if
+	// styled separately; if empty no style assigned
+	style, markerStyle string
 	// The text for this block; if the line's length is
 	// greater than the terminal width, it is word-wrapped
 	// into a number of lines
@@ -120,30 +277,44 @@ type block struct {
 // type of the line (URL, bullet list item, header, etc) and a
 // slice of lines representing a word-wrapped sequence of text.
 func NewBlock(width, margin int, preformatted bool, line string) block {
+	return newBlock(outputOptions{}, width, margin, preformatted, line)
+}
+
+// newBlock creates a block as per NewBlock, wrapping text according to
+// the supplied options.
+func newBlock(o outputOptions, width, margin int, preformatted bool, line string) block {
 	lineType := getLineType(preformatted, line)
-	s := line
 
 	// strip any prefixes
-	s = s[len(lineType.prefix):]
+	s := line[len(lineType.prefix):]
 
-	b := block{
-		lineType: lineType,
+	switch {
+	case preformatted:
+		// Preformatted text output as-is
+		return block{lineType: lineType, lines: []string{s}}
+	case lineType == link:
+		return block{lineType: lineType, lines: []string{parseLink(s)}}
+	case lineType == preformattedToggle:
+		return block{lineType: lineType}
 	}
 
-	// TODO revisit this
-	if preformatted {
-		// Preformatted text output as-is
-		b.lines = append(b.lines, s)
-	} else if lineType == link {
-		b.lines = []string{parseLink(s)}
-	} else if lineType != preformattedToggle {
-		// Available width is calculated as:
-		// the current terminal width - (L + R margin) - prefix width
-		availableWidth := width - margin*2 - (len(lineType.prefix) + 1)
-		b.lines = wrap(availableWidth, s)
+	return o.wrapBlock(lineType, width, margin, s)
+}
+
+// wrapBlock creates a block of the supplied line type, its text
+// word-wrapped to fit between the margins.
+func (o outputOptions) wrapBlock(lineType lineType, width, margin int, s string) block {
+	// Available width is calculated as:
+	// the current terminal width - (L + R margin) - marker width
+	availableWidth := width - margin*2 - (displayWidth(lineType.marker) + 1)
+
+	// Only body text is justified, not headings
+	wo := o.wrapOptions
+	if lineType == header || lineType == header2 || lineType == header3 {
+		wo.justify = false
 	}
 
-	return b
+	return block{lineType: lineType, lines: wo.wrap(availableWidth, s)}
 }
 
 // String outputs all lines in this block as a single string.
@@ -154,26 +325,23 @@ func (b *block) String(margin int) string {
 		// Generate margin
 		builder.WriteString(strings.Repeat(" ", margin))
 
-		if b.lineType.Colour != nil {
-			WriteAnsi16mColour(&builder, *b.lineType.Colour)
-		}
-
-		// Output prefix
-		if b.lineType != link && b.lineType.prefix != "" {
-			// TODO - not happy with this, revisit
-			if i == 0 || b.lineType != listItem && i > 0 {
-				builder.WriteString(b.lineType.prefix)
-				builder.WriteString(" ")
-			} else if b.lineType == listItem {
-				builder.WriteString("  ")
+		marker := b.marker(i)
+		if b.markerStyle != "" {
+			// Style the marker itself, not the space following it
+			if glyph := strings.TrimRight(marker, " "); glyph != "" {
+				builder.WriteString(b.markerStyle + glyph + Reset)
+				marker = marker[len(glyph):]
 			}
 		}
 
+		builder.WriteString(b.style)
+		builder.WriteString(marker)
+
 		// add line
 		builder.WriteString(line)
 
-		if b.lineType.Colour != nil {
-			builder.WriteString(Close)
+		if b.style != "" {
+			builder.WriteString(Reset)
 		}
 
 		builder.WriteString("\n")
@@ -182,37 +350,18 @@ func (b *block) String(margin int) string {
 	return builder.String()
 }
 
-// wrap takes the supplied line and wraps it on word boundaries
-// based on the supplied width. It returns a slice of wrapped lines.
-func wrap(width int, line string) []string {
-	var wrapped []string
-
-	if len(line) == 0 {
-		wrapped = append(wrapped, line)
-		return wrapped
-	}
-
-	words := strings.Fields(strings.TrimSpace(line))
-
-	// May just have spaces on a line
-	if len(words) == 0 {
-		return wrapped
-	}
-
-	wrappedLine := words[0]
-	spaceLeft := width - len(wrappedLine)
-	for _, word := range words[1:] {
-		if len(word)+1 > spaceLeft {
-			wrapped = append(wrapped, wrappedLine)
-			wrappedLine = word
-			spaceLeft = width - len(wrappedLine)
-		} else {
-			wrappedLine += " " + word
-			spaceLeft -= 1 + len(word)
-		}
+// marker returns the marker to output before the ith line of the
+// block. Markers are repeated on wrapped lines, except for list items,
+// whose wrapped lines are indented to line up with the first.
+func (b *block) marker(i int) string {
+	switch {
+	case b.lineType.marker == "":
+		return ""
+	case b.lineType == listItem && i > 0:
+		return strings.Repeat(" ", displayWidth(b.lineType.marker)+1)
+	default:
+		return b.lineType.marker + " "
 	}
-
-	return append(wrapped, wrappedLine)
 }
 
 // parseLink parses the supplied URL line type into a formatted string.
@@ -231,58 +380,76 @@ func wrap(width int, line string) []string {
 // Note that URL lines have already had their prefix stripped when
 // parsing.
 func parseLink(line string) string {
+	return formatLink(splitLink(line))
+}
+
+// formatLink formats the supplied link URL and label for output inline,
+// as "label [url]".
+func formatLink(url, label string) string {
+	if label == "" {
+		// No link name specified, just a URL
+		return fmt.Sprintf("[%s]", url)
+	}
+
+	return fmt.Sprintf("%s [%s]", label, url)
+}
+
+// splitLink splits the supplied URL line, minus its prefix, into its
+// URL and user-friendly link name (which may be empty).
+func splitLink(line string) (url, label string) {
 	cutSet := " \t"
 	line = strings.TrimLeft(line, cutSet)
 
-	var link string
-
 	// Get index between URL and link name
 	idx := strings.IndexAny(line, cutSet)
-	if idx > 0 {
-		link = fmt.Sprintf("%s [%s]", strings.Trim(line[idx:], cutSet),
-			strings.Trim(line[:idx], cutSet))
-	} else {
-		// No link name specified, just a URL
-		link = fmt.Sprintf("[%s]", strings.Trim(line, cutSet))
+	if idx < 0 {
+		return strings.Trim(line, cutSet), ""
 	}
 
-	return link
+	return line[:idx], strings.Trim(line[idx:], cutSet)
 }
 
 // ExtractLinks constructs a map of links and their link text from
-// the provided io.Reader.
-func ExtractLinks(r io.Reader) map[string]string {
+// the provided io.Reader. Given a Base URL, relative links are
+// resolved against it, and links to other capsules may be marked with
+// MarkExternal.
+//
+// Deprecated: the map loses the links' order, and all but the last
+// label of duplicate URLs. Use ExtractPageLinks instead.
+func ExtractLinks(r io.Reader, opts ...Option) map[string]string {
+	var o outputOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
 	links := make(map[string]string)
 
-	cutSet := " \t"
-	preformatted := false
-
-	s := bufio.NewScanner(r)
-	for s.Scan() {
-		line := s.Text()
-		if strings.HasPrefix(line, preformattedToggle.prefix) {
-			preformatted = !preformatted
-			continue
-		}
-		if preformatted {
-			continue
-		}
-		if strings.HasPrefix(line, link.prefix) {
-			// strip any prefixes
-			line = line[len(link.prefix):]
-			// Strip any trailing stuff left of the cutset
-			line = strings.TrimLeft(line, cutSet)
-
-			// Get index between URL and link name
-			idx := strings.IndexAny(line, cutSet)
-			if idx > 0 {
-				links[strings.Trim(line[:idx], cutSet)] = strings.Trim(line[idx:], cutSet)
-			} else {
-				// No link name specified, just a URL
-				links[strings.Trim(line, cutSet)] = ""
-			}
+	// Errors reading are ignored, as they always have been here
+	pageLinks, _ := ExtractPageLinks(r, opts...)
+	for _, l := range pageLinks {
+		if o.markExternal && l.External {
+			l.Label = strings.TrimSpace(l.Label + " " + externalMarker)
 		}
+		links[l.URL] = l.Label
 	}
 
 	return links
 }
+
+// ExtractAltText returns the alt text of each preformatted block in the
+// provided io.Reader, in document order. Blocks without alt text are
+// included as empty strings, so callers can find blocks lacking it.
+func ExtractAltText(r io.Reader) []string {
+	var alts []string
+
+	p := NewParser(r)
+	for {
+		line, err := p.Next()
+		if err != nil {
+			// Errors reading end the list, as they always have here
+			return alts
+		}
+		if pre, ok := line.(Preformatted); ok {
+			alts = append(alts, pre.Alt)
+		}
+	}
+}
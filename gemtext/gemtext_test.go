@@ -5,6 +5,7 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
@@ -51,6 +52,73 @@ func TestGetLineType(t *testing.T) {
 	}
 }
 
+// TestLineTypeConformance checks line classification against the
+// examples and rules in the gemtext specification.
+func TestLineTypeConformance(t *testing.T) {
+	testCases := []struct {
+		line           string
+		isPreformatted bool
+		expected       lineType
+	}{
+		// Text lines - anything not matching another line type
+		{line: "", expected: text},
+		{line: "Just some text", expected: text},
+		{line: " # indented hash", expected: text},
+		{line: " * indented asterisk", expected: text},
+		{line: " => indented arrow", expected: text},
+		{line: "= >not quite a link", expected: text},
+		// List items need an asterisk followed by a space
+		{line: "* Mercury", expected: listItem},
+		{line: "* Gemini", expected: listItem},
+		{line: "* Apollo", expected: listItem},
+		{line: "* ", expected: listItem},
+		{line: "*", expected: text},
+		{line: "*emphasised* text", expected: text},
+		{line: "**bold** text", expected: text},
+		{line: "*\ttab, not a space", expected: text},
+		// Headings; whitespace after the "#"s is optional, so the spec
+		// makes hashtags at the start of a line headings too
+		{line: "# Heading", expected: header},
+		{line: "## Sub-heading", expected: header2},
+		{line: "### Sub-subheading", expected: header3},
+		{line: "#Heading", expected: header},
+		{line: "#hashtag", expected: header},
+		{line: "#### Four is treated as three", expected: header3},
+		// Links
+		{line: "=> gemini://example.org/", expected: link},
+		{line: "=> gemini://example.org/ An example link", expected: link},
+		{line: "=> gemini://example.org/foo\tAnother example link at the same host", expected: link},
+		{line: "=> foo/bar/baz.txt\tA relative link", expected: link},
+		{line: "=> \tgopher://example.org:70/1 A gopher link", expected: link},
+		{line: "=>gemini://example.org/", expected: link},
+		// Quotes; no whitespace is required
+		{line: "> Quoted text", expected: quoted},
+		{line: ">Quoted text", expected: quoted},
+		{line: ">", expected: quoted},
+		// Preformatted toggles, with and without alt text
+		{line: "```", expected: preformattedToggle},
+		{line: "```go", expected: preformattedToggle},
+		{line: "``` ASCII art of a rocket", expected: preformattedToggle},
+		{line: "``", expected: text},
+		// In preformatted mode, only toggles are recognised
+		{line: "```", isPreformatted: true, expected: preformattedToggle},
+		{line: "``` trailing text", isPreformatted: true, expected: preformattedToggle},
+		{line: "# Not a heading", isPreformatted: true, expected: preformatted},
+		{line: "=> not a link", isPreformatted: true, expected: preformatted},
+		{line: "* not a list item", isPreformatted: true, expected: preformatted},
+		{line: "> not a quote", isPreformatted: true, expected: preformatted},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%q", tc.line), func(t *testing.T) {
+			got := getLineType(tc.isPreformatted, tc.line)
+			if got != tc.expected {
+				t.Errorf("got %v, want %v", got, tc.expected)
+			}
+		})
+	}
+}
+
 func TestWrapLine(t *testing.T) {
 	testCases := map[string]struct {
 		lineType     lineType
@@ -72,6 +140,21 @@ func TestWrapLine(t *testing.T) {
 			line:         "     ",
 			wrappedLines: []string{},
 		},
+		"accented": {
+			lineType:     text,
+			line:         "éèê éèê éèê éèê éèê éèê éèê",
+			wrappedLines: []string{"éèê éèê éèê éèê éèê éèê", "éèê"},
+		},
+		"cjk": {
+			lineType:     text,
+			line:         "日本語のテキストは空白なしで折り返されます。",
+			wrappedLines: []string{"日本語のテキストは空白な", "しで折り返されます。"},
+		},
+		"emoji": {
+			lineType:     text,
+			line:         "🚀🚀 🚀🚀 🚀🚀 🚀🚀 🚀🚀 🚀🚀",
+			wrappedLines: []string{"🚀🚀 🚀🚀 🚀🚀 🚀🚀 🚀🚀", "🚀🚀"},
+		},
 	}
 
 	for name, tc := range testCases {
@@ -172,7 +255,7 @@ func TestBlockString(t *testing.T) {
 				lineType: listItem,
 				lines:    []string{"line one of the text block", "line two of the text block"},
 			},
-			expected: fmt.Sprintf("%s%s line one of the text block\n%s  line two of the text block\n", marginStr, listItem.prefix, marginStr),
+			expected: fmt.Sprintf("%s%s line one of the text block\n%s  line two of the text block\n", marginStr, listItem.marker, marginStr),
 		},
 		"quote block": {
 			block: block{
@@ -238,7 +321,10 @@ func TestOutput(t *testing.T) {
 	conf["header3"] = "#ffff00"
 	conf["link"] = "#00ff00"
 	conf["quoted"] = "#ff00ff"
-	Configure(conf)
+	theme, err := ThemeFromConfig(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	paths, err := filepath.Glob(filepath.Join("testdata", "*.input"))
 	if err != nil {
@@ -258,7 +344,7 @@ func TestOutput(t *testing.T) {
 
 			var b []byte
 			got := bytes.NewBuffer(b)
-			Output(30, 2, input, got)
+			Output(30, 2, input, got, Colours(theme))
 
 			golden := filepath.Join("testdata", testName+".golden")
 			f, err := os.OpenFile(golden, os.O_RDWR, 0644)
@@ -313,3 +399,138 @@ func TestExtractLinks(t *testing.T) {
 		}
 	}
 }
+
+func TestOutputAltText(t *testing.T) {
+	input := "```A rocket\n  /\\\n```\n```\nno alt text\n```\n"
+	marginStr := strings.Repeat(" ", margin)
+
+	testCases := map[string]struct {
+		show     bool
+		expected string
+	}{
+		"hidden": {
+			expected: fmt.Sprintf("%s  /\\\n%sno alt text\n", marginStr, marginStr),
+		},
+		"shown": {
+			show:     true,
+			expected: fmt.Sprintf("%s[A rocket]\n%s  /\\\n%sno alt text\n", marginStr, marginStr, marginStr),
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var got bytes.Buffer
+			Output(40, margin, strings.NewReader(input), &got, AltText(tc.show))
+			if got.String() != tc.expected {
+				t.Errorf("got '%s', want '%s'", got.String(), tc.expected)
+			}
+		})
+	}
+}
+
+func TestExtractAltText(t *testing.T) {
+	input := "```go\nfunc main() {}\n```\ntext\n```\nart\n``` closing text ignored\n``` A rocket \n" +
+		strings.Repeat("x", 70000) + "\n```\n```after a line over 64KiB\n"
+	expected := []string{"go", "", "A rocket", "after a line over 64KiB"}
+
+	got := ExtractAltText(strings.NewReader(input))
+	if len(got) != len(expected) {
+		t.Fatalf("got %d alt texts, want %d", len(got), len(expected))
+	}
+	for i, alt := range got {
+		if alt != expected[i] {
+			t.Errorf("alt text %d does not match; got '%s', want '%s'", i, alt, expected[i])
+		}
+	}
+}
+
+func TestOutputMaxWidth(t *testing.T) {
+	input := "# Heading\none two three four five six seven eight\n"
+
+	testCases := map[string]struct {
+		width    int
+		maxWidth int
+		expected string
+	}{
+		"centred": {
+			width:    41,
+			maxWidth: 21,
+			expected: "" +
+				"          # Heading\n" +
+				"          one two three four\n" +
+				"          five six seven eight\n",
+		},
+		"narrower terminal": {
+			width:    25,
+			maxWidth: 30,
+			expected: "" +
+				"  # Heading\n" +
+				"  one two three four\n" +
+				"  five six seven eight\n",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var got bytes.Buffer
+			Output(tc.width, 2, strings.NewReader(input), &got, MaxWidth(tc.maxWidth))
+			if got.String() != tc.expected {
+				t.Errorf("got '%s', want '%s'", got.String(), tc.expected)
+			}
+		})
+	}
+}
+
+func TestOutputLinkReferences(t *testing.T) {
+	input := "# One\n=> /a Link A\n=> b\n\n## Two\n=> gemini://other.url/ Link C\n"
+	base, err := url.Parse("gemini://some.url/dir/page.gmi")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := map[string]struct {
+		opts     []Option
+		expected string
+	}{
+		"inline": {
+			expected: "# One\nLink A [/a]\n[b]\n\n## Two\nLink C [gemini://other.url/]\n",
+		},
+		"numbered": {
+			opts:     []Option{NumberLinks(true)},
+			expected: "# One\n[1] Link A\n[2] b\n\n## Two\n[3] Link C\n",
+		},
+		"references at end": {
+			opts: []Option{LinkReferences(ReferencesEnd)},
+			expected: "# One\n[1] Link A\n[2] b\n\n## Two\n[3] Link C\n" +
+				"\n[1] /a\n[2] b\n[3] gemini://other.url/\n",
+		},
+		"references by section": {
+			opts: []Option{LinkReferences(ReferencesSection)},
+			expected: "# One\n[1] Link A\n[2] b\n\n[1] /a\n[2] b\n\n## Two\n[3] Link C\n" +
+				"\n[3] gemini://other.url/\n",
+		},
+		"resolved against base": {
+			opts: []Option{LinkReferences(ReferencesEnd), Base(base)},
+			expected: "# One\n[1] Link A\n[2] gemini://some.url/dir/b\n\n## Two\n[3] Link C\n" +
+				"\n[1] gemini://some.url/a\n[2] gemini://some.url/dir/b\n[3] gemini://other.url/\n",
+		},
+		"inline resolved against base": {
+			opts:     []Option{Base(base)},
+			expected: "# One\nLink A [gemini://some.url/a]\n[gemini://some.url/dir/b]\n\n## Two\nLink C [gemini://other.url/]\n",
+		},
+		"external links marked": {
+			opts:     []Option{Base(base), MarkExternal(true), NumberLinks(true)},
+			expected: "# One\n[1] Link A\n[2] gemini://some.url/dir/b\n\n## Two\n[3] Link C ↗\n",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var got bytes.Buffer
+			Output(80, 0, strings.NewReader(input), &got, tc.opts...)
+			if got.String() != tc.expected {
+				t.Errorf("got '%s', want '%s'", got.String(), tc.expected)
+			}
+		})
+	}
+}
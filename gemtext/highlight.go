@@ -0,0 +1,323 @@
+package gemtext
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// tokenKind is the kind of a token in highlighted source code.
+type tokenKind int
+
+const (
+	plainToken tokenKind = iota
+	keywordToken
+	stringToken
+	commentToken
+	numberToken
+)
+
+// language describes the lexical syntax of a programming language,
+// enough to highlight its keywords, strings, comments and numbers.
+type language struct {
+	keywords map[string]bool
+	// Prefixes of comments running to the end of the line
+	lineComments []string
+	// Delimiters of comments that may span lines, if any
+	blockComment [2]string
+	// Characters quoting strings, in which backslashes escape
+	quotes string
+	// Delimiters of strings that may span lines, without escapes
+	multiline []string
+}
+
+// lexState is the state carried between the lines of a block of
+// highlighted code: the delimiter and kind of any comment or string
+// left open at the end of the previous line.
+type lexState struct {
+	close string
+	kind  tokenKind
+}
+
+// token is a run of source code of a single kind.
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// words returns a set of the supplied space-separated words.
+func words(s string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range strings.Fields(s) {
+		set[word] = true
+	}
+
+	return set
+}
+
+var (
+	goLanguage = &language{
+		keywords: words(`break case chan const continue default defer else fallthrough
+			for func go goto if import interface map package range return select
+			struct switch type var true false nil iota bool byte complex64
+			complex128 error float32 float64 int int8 int16 int32 int64 rune
+			string uint uint8 uint16 uint32 uint64 uintptr any`),
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       `"'`,
+		multiline:    []string{"`"},
+	}
+	cLanguage = &language{
+		keywords: words(`auto break case char const continue default do double else enum
+			extern float for goto if inline int long register return short signed
+			sizeof static struct switch typedef union unsigned void volatile while
+			bool true false NULL nullptr class namespace template typename public
+			private protected virtual new delete this using`),
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       `"'`,
+	}
+	pythonLanguage = &language{
+		keywords: words(`and as assert async await break class continue def del elif
+			else except finally for from global if import in is lambda nonlocal not
+			or pass raise return try while with yield True False None self`),
+		lineComments: []string{"#"},
+		quotes:       `"'`,
+		multiline:    []string{`"""`, `'''`},
+	}
+	javascriptLanguage = &language{
+		keywords: words(`async await break case catch class const continue debugger
+			default delete do else export extends finally for function if import in
+			instanceof let new of return static super switch this throw try typeof
+			var void while with yield true false null undefined interface type enum
+			implements`),
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       `"'`,
+		multiline:    []string{"`"},
+	}
+	rustLanguage = &language{
+		keywords: words(`as async await break const continue crate dyn else enum extern
+			false fn for if impl in let loop match mod move mut pub ref return self
+			Self static struct super trait true type unsafe use where while bool
+			char str u8 u16 u32 u64 u128 usize i8 i16 i32 i64 i128 isize f32 f64`),
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       `"`,
+	}
+	shellLanguage = &language{
+		keywords: words(`if then else elif fi case esac for while until do done in
+			function select return exit break continue local export readonly set
+			unset shift source`),
+		lineComments: []string{"#"},
+		quotes:       `"'`,
+	}
+	jsonLanguage = &language{
+		keywords: words(`true false null`),
+		quotes:   `"`,
+	}
+)
+
+// languages maps the names that alt text may give languages by to
+// their syntax.
+var languages = map[string]*language{
+	"go":         goLanguage,
+	"golang":     goLanguage,
+	"c":          cLanguage,
+	"h":          cLanguage,
+	"cpp":        cLanguage,
+	"c++":        cLanguage,
+	"python":     pythonLanguage,
+	"py":         pythonLanguage,
+	"javascript": javascriptLanguage,
+	"js":         javascriptLanguage,
+	"typescript": javascriptLanguage,
+	"ts":         javascriptLanguage,
+	"rust":       rustLanguage,
+	"rs":         rustLanguage,
+	"sh":         shellLanguage,
+	"bash":       shellLanguage,
+	"shell":      shellLanguage,
+	"zsh":        shellLanguage,
+	"json":       jsonLanguage,
+}
+
+// Highlight sets whether preformatted blocks are syntax highlighted,
+// in the styles set by the theme, when their alt text names a known
+// language, as in "```go".
+func Highlight(highlight bool) Option {
+	return func(o *outputOptions) {
+		o.highlight = highlight
+	}
+}
+
+// languageOf returns the syntax of the language named by the first
+// word of the supplied alt text, if known.
+func languageOf(alt string) (*language, bool) {
+	fields := strings.Fields(alt)
+	if len(fields) == 0 {
+		return nil, false
+	}
+
+	lang, ok := languages[strings.ToLower(fields[0])]
+	return lang, ok
+}
+
+// highlightLines returns the supplied lines of code in the language,
+// with their tokens styled by the theme.
+func (o outputOptions) highlightLines(lang *language, lines []string) []string {
+	var state lexState
+	highlighted := make([]string, len(lines))
+	for i, line := range lines {
+		var b strings.Builder
+		for _, tok := range lang.tokens(line, &state) {
+			b.WriteString(o.inlineStyle(tok.text, o.theme.syntaxStyle(tok.kind), o.theme.Preformatted))
+		}
+		highlighted[i] = b.String()
+	}
+
+	return highlighted
+}
+
+// tokens splits a line of code into tokens, continuing from and
+// updating the supplied state. Adjacent tokens of the same kind are
+// merged.
+func (l *language) tokens(line string, state *lexState) []token {
+	var toks []token
+	add := func(kind tokenKind, text string) {
+		if n := len(toks); n > 0 && toks[n-1].kind == kind {
+			toks[n-1].text += text
+			return
+		}
+		toks = append(toks, token{kind: kind, text: text})
+	}
+
+	i := 0
+	if state.close != "" {
+		end := strings.Index(line, state.close)
+		if end < 0 {
+			add(state.kind, line)
+			return toks
+		}
+		i = end + len(state.close)
+		add(state.kind, line[:i])
+		state.close = ""
+	}
+
+	for i < len(line) {
+		rest := line[i:]
+		r, size := utf8.DecodeRuneInString(rest)
+
+		if prefix, ok := hasAnyPrefix(rest, l.lineComments); ok && commentStarts(line, i, prefix) {
+			add(commentToken, rest)
+			break
+		}
+		if open := l.blockComment[0]; open != "" && strings.HasPrefix(rest, open) {
+			n := closeRun(line, i, len(open), l.blockComment[1], commentToken, state)
+			add(commentToken, line[i:n])
+			i = n
+			continue
+		}
+		if delim, ok := hasAnyPrefix(rest, l.multiline); ok {
+			n := closeRun(line, i, len(delim), delim, stringToken, state)
+			add(stringToken, line[i:n])
+			i = n
+			continue
+		}
+
+		switch {
+		case strings.ContainsRune(l.quotes, r):
+			n := quotedString(rest, byte(r))
+			add(stringToken, rest[:n])
+			i += n
+		case unicode.IsDigit(r):
+			n := len(rest) - len(strings.TrimLeftFunc(rest, isNumberPart))
+			add(numberToken, rest[:n])
+			i += n
+		case isIdentStart(r):
+			n := len(rest) - len(strings.TrimLeftFunc(rest, isIdentPart))
+			kind := plainToken
+			if l.keywords[rest[:n]] {
+				kind = keywordToken
+			}
+			add(kind, rest[:n])
+			i += n
+		default:
+			add(plainToken, rest[:size])
+			i += size
+		}
+	}
+
+	return toks
+}
+
+// commentStarts reports whether a line comment starts with the prefix
+// at index i of the line. A "#" only starts a comment at the start of
+// a word, so that it may be used within words, as in shell's "$#".
+func commentStarts(line string, i int, prefix string) bool {
+	if prefix != "#" || i == 0 {
+		return true
+	}
+
+	before, _ := utf8.DecodeLastRuneInString(line[:i])
+	return unicode.IsSpace(before)
+}
+
+// closeRun returns the index just past the end of a comment or string
+// starting at index i of the line, opened by a delimiter of the
+// supplied length and closed by close. If it isn't closed on this
+// line, the state is left waiting for close on the next, and the
+// whole of the rest of the line is taken.
+func closeRun(line string, i, open int, close string, kind tokenKind, state *lexState) int {
+	start := i + open
+	if end := strings.Index(line[start:], close); end >= 0 {
+		return start + end + len(close)
+	}
+
+	state.close = close
+	state.kind = kind
+	return len(line)
+}
+
+// quotedString returns the length of the string quoted by quote at
+// the start of s, honouring backslash escapes. Unterminated strings
+// run to the end of the line.
+func quotedString(s string, quote byte) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case quote:
+			return i + 1
+		}
+	}
+
+	return len(s)
+}
+
+// hasAnyPrefix returns the first of the prefixes that s starts with.
+func hasAnyPrefix(s string, prefixes []string) (string, bool) {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return prefix, true
+		}
+	}
+
+	return "", false
+}
+
+// isIdentStart reports whether r may start an identifier.
+func isIdentStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+// isIdentPart reports whether r may be part of an identifier.
+func isIdentPart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// isNumberPart reports whether r may be part of a numeric literal, in
+// any of the usual bases and notations, e.g. 0x1f, 1_000 or 1.5e3.
+func isNumberPart(r rune) bool {
+	return r == '.' || r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,149 @@
+package gemtext
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTokens(t *testing.T) {
+	testCases := map[string]struct {
+		lang     string
+		lines    []string
+		expected [][]token
+	}{
+		"keywords and identifiers": {
+			lang:  "go",
+			lines: []string{"func main() {"},
+			expected: [][]token{{
+				{keywordToken, "func"}, {plainToken, " main() {"},
+			}},
+		},
+		"strings and numbers": {
+			lang:  "go",
+			lines: []string{`x := "a \"b\"" + 'c' + 0x1f + 1.5e3`},
+			expected: [][]token{{
+				{plainToken, "x := "}, {stringToken, `"a \"b\""`}, {plainToken, " + "},
+				{stringToken, "'c'"}, {plainToken, " + "}, {numberToken, "0x1f"},
+				{plainToken, " + "}, {numberToken, "1.5e3"},
+			}},
+		},
+		"digits within identifiers": {
+			lang:     "go",
+			lines:    []string{"int64"},
+			expected: [][]token{{{keywordToken, "int64"}}},
+		},
+		"line comment": {
+			lang:  "go",
+			lines: []string{`return // "not a string"`},
+			expected: [][]token{{
+				{keywordToken, "return"}, {plainToken, " "}, {commentToken, `// "not a string"`},
+			}},
+		},
+		"block comment spanning lines": {
+			lang:  "c",
+			lines: []string{"int x; /* one", "two", "three */ return x;"},
+			expected: [][]token{
+				{{keywordToken, "int"}, {plainToken, " x; "}, {commentToken, "/* one"}},
+				{{commentToken, "two"}},
+				{{commentToken, "three */"}, {plainToken, " "}, {keywordToken, "return"}, {plainToken, " x;"}},
+			},
+		},
+		"raw string spanning lines": {
+			lang:  "go",
+			lines: []string{"s := `one", `"two"`, "` + s"},
+			expected: [][]token{
+				{{plainToken, "s := "}, {stringToken, "`one"}},
+				{{stringToken, `"two"`}},
+				{{stringToken, "`"}, {plainToken, " + s"}},
+			},
+		},
+		"triple quoted string": {
+			lang:  "python",
+			lines: []string{`def f(): """Doc""" # comment`},
+			expected: [][]token{{
+				{keywordToken, "def"}, {plainToken, " f(): "}, {stringToken, `"""Doc"""`},
+				{plainToken, " "}, {commentToken, "# comment"},
+			}},
+		},
+		"hash within words": {
+			lang:  "sh",
+			lines: []string{`echo $# # args`},
+			expected: [][]token{{
+				{plainToken, "echo $# "}, {commentToken, "# args"},
+			}},
+		},
+		"unterminated string": {
+			lang:     "json",
+			lines:    []string{`{"key": true, "open`},
+			expected: [][]token{{{plainToken, "{"}, {stringToken, `"key"`}, {plainToken, ": "}, {keywordToken, "true"}, {plainToken, ", "}, {stringToken, `"open`}}},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			lang, ok := languageOf(tc.lang)
+			if !ok {
+				t.Fatalf("unknown language '%s'", tc.lang)
+			}
+
+			var state lexState
+			for i, line := range tc.lines {
+				if got := lang.tokens(line, &state); !reflect.DeepEqual(got, tc.expected[i]) {
+					t.Errorf("line %d: got %v, want %v", i, got, tc.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLanguageOf(t *testing.T) {
+	testCases := map[string]bool{
+		"":                  false,
+		"Go":                true,
+		"python script.py":  true,
+		"A rocket":          false,
+		"   json  ":         true,
+		"figlet banner art": false,
+	}
+
+	for alt, expected := range testCases {
+		if _, got := languageOf(alt); got != expected {
+			t.Errorf("alt text '%s': got %t, want %t", alt, got, expected)
+		}
+	}
+}
+
+func TestOutputHighlight(t *testing.T) {
+	input := "```go\nreturn 1 // one\n```\n```A rocket\nif\n```\n"
+	theme := Theme{
+		Preformatted:  Style{Dim: true},
+		SyntaxKeyword: Style{Bold: true},
+		SyntaxComment: Style{Italic: true},
+	}
+
+	testCases := map[string]struct {
+		opts     []Option
+		expected string
+	}{
+		"off by default": {
+			expected: "\u001B[2mreturn 1 // one\u001B[0m\n\u001B[2mif\u001B[0m\n",
+		},
+		"highlighted": {
+			opts: []Option{Highlight(true)},
+			expected: "\u001B[2m\u001B[1;2mreturn\u001B[0m\u001B[2m 1 \u001B[2;3m// one\u001B[0m\u001B[2m\u001B[0m\n" +
+				"\u001B[2mif\u001B[0m\n",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var got bytes.Buffer
+			Output(80, 0, strings.NewReader(input), &got, append(tc.opts, Colours(theme))...)
+			if got.String() != tc.expected {
+				t.Errorf("got %q, want %q", got.String(), tc.expected)
+			}
+		})
+	}
+}
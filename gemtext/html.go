@@ -0,0 +1,263 @@
+package gemtext
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"io"
+	"net/url"
+	"strings"
+	"unicode"
+)
+
+// DefaultStylesheet is the CSS included in full HTML pages, unless
+// replaced with Stylesheet.
+const DefaultStylesheet = `body {
+  max-width: 40em;
+  margin: 2em auto;
+  padding: 0 1em;
+  font-family: sans-serif;
+  line-height: 1.5;
+}
+pre {
+  overflow-x: auto;
+  padding: 0.5em;
+  background: #f4f4f4;
+}
+blockquote {
+  margin-left: 0;
+  padding-left: 1em;
+  border-left: 3px solid #ccc;
+}
+ul.links {
+  list-style: none;
+  padding-left: 0;
+}
+ul.links li::before {
+  content: "⇒ ";
+}
+`
+
+// pageTemplate is the template for full HTML pages.
+var pageTemplate = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+{{.CSS}}</style>
+</head>
+<body>
+{{.Body}}</body>
+</html>
+`))
+
+// FullPage sets whether HTML is output as a complete page, including
+// a stylesheet, rather than just the body's content.
+func FullPage(page bool) Option {
+	return func(o *outputOptions) {
+		o.fullPage = page
+	}
+}
+
+// Stylesheet sets the CSS included in full HTML pages, replacing
+// DefaultStylesheet.
+func Stylesheet(css string) Option {
+	return func(o *outputOptions) {
+		o.css = css
+	}
+}
+
+// OutputHTML converts the gemtext in the supplied reader to HTML,
+// writing it to w. Consecutive list items and links are grouped into
+// lists, headings are given ids derived from their text for use as
+// anchors, and preformatted blocks are labelled with their alt text.
+//
+// Relative links are resolved against any Base URL. Links with
+// schemes that could run script, such as javascript:, are neutered.
+func OutputHTML(r io.Reader, w io.Writer, opts ...Option) error {
+	return Render(r, NewHTMLRenderer(w, opts...))
+}
+
+// htmlRenderer renders gemtext as HTML.
+type htmlRenderer struct {
+	w io.Writer
+	o outputOptions
+
+	body  strings.Builder
+	title string
+	ids   map[string]bool
+	// The element grouping the lines before this one, if still open
+	open string
+}
+
+// NewHTMLRenderer returns a Renderer converting gemtext to HTML, as
+// per OutputHTML.
+func NewHTMLRenderer(w io.Writer, opts ...Option) Renderer {
+	h := &htmlRenderer{w: w, o: outputOptions{css: DefaultStylesheet}}
+	for _, opt := range opts {
+		opt(&h.o)
+	}
+
+	return h
+}
+
+func (h *htmlRenderer) Start() error {
+	h.body.Reset()
+	h.title = ""
+	h.ids = make(map[string]bool)
+	h.open = ""
+	return nil
+}
+
+func (h *htmlRenderer) Text(line Text) error {
+	h.group("")
+	// Blank lines only space out the gemtext
+	if strings.TrimSpace(line.Text) != "" {
+		fmt.Fprintf(&h.body, "<p>%s</p>\n", html.EscapeString(line.Text))
+	}
+	return nil
+}
+
+func (h *htmlRenderer) Link(line Link) error {
+	h.group(`<ul class="links">`)
+	url := resolve(h.o.base, line.URL)
+	label := line.Label
+	if label == "" {
+		label = url
+	}
+	fmt.Fprintf(&h.body, "<li><a href=\"%s\">%s</a></li>\n", html.EscapeString(safeURL(url)), html.EscapeString(label))
+	return nil
+}
+
+func (h *htmlRenderer) Heading(line Heading) error {
+	h.group("")
+	if h.title == "" {
+		h.title = line.Text
+	}
+	fmt.Fprintf(&h.body, "<h%d id=\"%s\">%s</h%d>\n", line.Level, headingID(h.ids, line.Text), html.EscapeString(line.Text), line.Level)
+	return nil
+}
+
+func (h *htmlRenderer) ListItem(line ListItem) error {
+	h.group("<ul>")
+	fmt.Fprintf(&h.body, "<li>%s</li>\n", html.EscapeString(line.Text))
+	return nil
+}
+
+func (h *htmlRenderer) Quote(line Quote) error {
+	h.group("<blockquote>")
+	fmt.Fprintf(&h.body, "<p>%s</p>\n", html.EscapeString(strings.TrimSpace(line.Text)))
+	return nil
+}
+
+func (h *htmlRenderer) Preformatted(line Preformatted) error {
+	h.group("")
+	h.body.WriteString("<pre")
+	if line.Alt != "" {
+		fmt.Fprintf(&h.body, " aria-label=\"%s\" title=\"%s\"", html.EscapeString(line.Alt), html.EscapeString(line.Alt))
+	}
+	h.body.WriteString(">")
+	h.body.WriteString(html.EscapeString(strings.Join(line.Lines, "\n")))
+	h.body.WriteString("</pre>\n")
+	return nil
+}
+
+func (h *htmlRenderer) End() error {
+	h.group("")
+
+	if !h.o.fullPage {
+		_, err := io.WriteString(h.w, h.body.String())
+		return err
+	}
+
+	return pageTemplate.Execute(h.w, struct {
+		Title string
+		CSS   template.CSS
+		Body  template.HTML
+	}{
+		Title: h.title,
+		CSS:   template.CSS(h.o.css),
+		Body:  template.HTML(h.body.String()),
+	})
+}
+
+// group opens the supplied element to group the following lines in,
+// closing any previous group first. An empty element closes the
+// previous group without opening another.
+func (h *htmlRenderer) group(element string) {
+	if element == h.open {
+		return
+	}
+
+	if h.open != "" {
+		h.body.WriteString(closingTag(h.open) + "\n")
+	}
+	if element != "" {
+		h.body.WriteString(element + "\n")
+	}
+	h.open = element
+}
+
+// closingTag returns the closing tag for the supplied opening tag.
+func closingTag(tag string) string {
+	name := strings.Trim(tag, "<>")
+	if i := strings.IndexByte(name, ' '); i >= 0 {
+		name = name[:i]
+	}
+
+	return "</" + name + ">"
+}
+
+// unsafeSchemes are URL schemes whose links could run script when
+// followed in a browser.
+var unsafeSchemes = map[string]bool{
+	"javascript": true,
+	"vbscript":   true,
+	"data":       true,
+}
+
+// safeURL returns the supplied URL, or "#" if following it could run
+// script.
+func safeURL(link string) string {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || unsafeSchemes[strings.ToLower(u.Scheme)] {
+		return "#"
+	}
+
+	return link
+}
+
+// headingID returns an id for a heading with the supplied text: its
+// letters and digits lowercased, with runs of anything else replaced by
+// hyphens. ids records those already used, so that headings with the
+// same text get unique ids, suffixed "-2", "-3" and so on.
+func headingID(ids map[string]bool, text string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			hyphen = false
+			continue
+		}
+		hyphen = true
+	}
+
+	slug := b.String()
+	if slug == "" {
+		slug = "section"
+	}
+
+	id := slug
+	for n := 2; ids[id]; n++ {
+		id = fmt.Sprintf("%s-%d", slug, n)
+	}
+	ids[id] = true
+
+	return id
+}
@@ -0,0 +1,130 @@
+package gemtext
+
+import (
+	"bytes"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOutputHTML(t *testing.T) {
+	base, err := url.Parse("gemini://some.url/dir/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := map[string]struct {
+		input    string
+		opts     []Option
+		expected string
+	}{
+		"text": {
+			input:    "Some text\n\nMore text\n",
+			expected: "<p>Some text</p>\n<p>More text</p>\n",
+		},
+		"escaping": {
+			input:    "<script>alert(\"&\")</script>\n",
+			expected: "<p>&lt;script&gt;alert(&#34;&amp;&#34;)&lt;/script&gt;</p>\n",
+		},
+		"list items grouped": {
+			input:    "* One\n* Two\nText\n* Three\n",
+			expected: "<ul>\n<li>One</li>\n<li>Two</li>\n</ul>\n<p>Text</p>\n<ul>\n<li>Three</li>\n</ul>\n",
+		},
+		"links grouped": {
+			input: "=> gemini://some.url/ Some URL\n=> /relative\n* Item\n",
+			expected: "<ul class=\"links\">\n" +
+				"<li><a href=\"gemini://some.url/\">Some URL</a></li>\n" +
+				"<li><a href=\"/relative\">/relative</a></li>\n" +
+				"</ul>\n<ul>\n<li>Item</li>\n</ul>\n",
+		},
+		"links resolved against base": {
+			input:    "=> ../page.gmi Page\n",
+			opts:     []Option{Base(base)},
+			expected: "<ul class=\"links\">\n<li><a href=\"gemini://some.url/page.gmi\">Page</a></li>\n</ul>\n",
+		},
+		"unsafe links": {
+			input: "=> javascript:alert(1) Click\n=> \"onclick=x Quoted\n",
+			expected: "<ul class=\"links\">\n" +
+				"<li><a href=\"#\">Click</a></li>\n" +
+				"<li><a href=\"&#34;onclick=x\">Quoted</a></li>\n" +
+				"</ul>\n",
+		},
+		"headings": {
+			input: "# Hello, World!\n## Hello world\n### Über 9000\n# ???\n",
+			expected: "<h1 id=\"hello-world\">Hello, World!</h1>\n" +
+				"<h2 id=\"hello-world-2\">Hello world</h2>\n" +
+				"<h3 id=\"über-9000\">Über 9000</h3>\n" +
+				"<h1 id=\"section\">???</h1>\n",
+		},
+		"quotes grouped": {
+			input:    "> One\n>Two\n",
+			expected: "<blockquote>\n<p>One</p>\n<p>Two</p>\n</blockquote>\n",
+		},
+		"preformatted": {
+			input:    "```A <rocket>\n  /\\\n <&>\n```\n```\nno alt\n```\n",
+			expected: "<pre aria-label=\"A &lt;rocket&gt;\" title=\"A &lt;rocket&gt;\">  /\\\n &lt;&amp;&gt;</pre>\n<pre>no alt</pre>\n",
+		},
+		"full page": {
+			input: "# Title & more\n",
+			opts:  []Option{FullPage(true), Stylesheet("p { color: red; }\n")},
+			expected: "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n" +
+				"<meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n" +
+				"<title>Title &amp; more</title>\n<style>\np { color: red; }\n</style>\n</head>\n" +
+				"<body>\n<h1 id=\"title-more\">Title &amp; more</h1>\n</body>\n</html>\n",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var got bytes.Buffer
+			if err := OutputHTML(strings.NewReader(tc.input), &got, tc.opts...); err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+			if got.String() != tc.expected {
+				t.Errorf("got '%s', want '%s'", got.String(), tc.expected)
+			}
+		})
+	}
+}
+
+func TestOutputHTMLGolden(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "*.input"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range paths {
+		_, fileName := filepath.Split(path)
+		testName := fileName[:len(fileName)-len(filepath.Ext(path))]
+
+		t.Run(testName, func(t *testing.T) {
+			input, err := os.Open(path)
+			if err != nil {
+				t.Fatalf("error reading test input file: %s", err)
+			}
+			defer input.Close()
+
+			var got bytes.Buffer
+			if err := OutputHTML(input, &got, FullPage(true)); err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+
+			golden := filepath.Join("testdata", testName+".html")
+			if *update {
+				if err := os.WriteFile(golden, got.Bytes(), 0644); err != nil {
+					t.Fatalf("error updating golden file '%s': %s", golden, err)
+				}
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("error reading test golden file: %s", err)
+			}
+			if !bytes.Equal(got.Bytes(), want) {
+				t.Errorf("got '%s', want '%s'", got.String(), want)
+			}
+		})
+	}
+}
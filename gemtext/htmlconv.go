@@ -0,0 +1,502 @@
+package gemtext
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// contentElements are elements counted when looking for the one holding
+// a document's main content.
+var contentElements = map[string]bool{
+	"p": true, "pre": true, "ul": true, "ol": true, "dl": true, "blockquote": true,
+	"table": true, "figure": true, "h1": true, "h2": true, "h3": true, "h4": true,
+	"h5": true, "h6": true,
+}
+
+// skippedElements are elements holding no article content.
+var skippedElements = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true,
+	"head": true, "nav": true, "aside": true, "footer": true, "form": true,
+	"iframe": true, "svg": true, "button": true, "input": true, "select": true,
+	"textarea": true, "object": true, "embed": true, "canvas": true,
+}
+
+// blockElements are elements starting a new gemtext line.
+var blockElements = map[string]bool{
+	"p": true, "div": true, "section": true, "article": true, "main": true,
+	"header": true, "figure": true, "figcaption": true, "dl": true, "dt": true,
+	"dd": true, "address": true, "details": true, "summary": true, "body": true,
+}
+
+// htmlNode is an element or text node of a parsed HTML document.
+type htmlNode struct {
+	// Element name, or empty for text nodes
+	tag      string
+	attrs    map[string]string
+	text     string
+	children []*htmlNode
+}
+
+// parseHTMLTree parses the supplied HTML into a tree of nodes, as a
+// browser would, closing unclosed and void elements.
+func parseHTMLTree(r io.Reader) (*htmlNode, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing HTML: %w", err)
+	}
+
+	return newHTMLNode(doc), nil
+}
+
+// newHTMLNode converts a node parsed by the html package, and those
+// below it, to a tree of nodes. Comments and doctypes are dropped, and
+// nil is returned for them.
+func newHTMLNode(n *html.Node) *htmlNode {
+	var node *htmlNode
+	switch n.Type {
+	case html.TextNode:
+		return &htmlNode{text: n.Data}
+	case html.DocumentNode:
+		node = &htmlNode{tag: "#document", attrs: make(map[string]string)}
+	case html.ElementNode:
+		node = &htmlNode{tag: n.Data, attrs: make(map[string]string)}
+		for _, a := range n.Attr {
+			node.attrs[a.Key] = a.Val
+		}
+	default:
+		return nil
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if child := newHTMLNode(c); child != nil {
+			node.children = append(node.children, child)
+		}
+	}
+
+	return node
+}
+
+// find returns the first element in the tree below n for which match
+// returns true, searching depth first.
+func (n *htmlNode) find(match func(*htmlNode) bool) *htmlNode {
+	for _, c := range n.children {
+		if c.tag != "" && match(c) {
+			return c
+		}
+		if found := c.find(match); found != nil {
+			return found
+		}
+	}
+
+	return nil
+}
+
+// textContent returns all the text below n, as written, with line
+// breaks as newlines.
+func (n *htmlNode) textContent() string {
+	if n.tag == "" {
+		return n.text
+	}
+	if n.tag == "br" {
+		return "\n"
+	}
+
+	var b strings.Builder
+	for _, c := range n.children {
+		b.WriteString(c.textContent())
+	}
+
+	return b.String()
+}
+
+// mainContent returns the element holding the document's main content:
+// its article or main element if it has one, or otherwise the element
+// with the most text in paragraphs, lists and other content elements
+// directly within it.
+func mainContent(root *htmlNode) *htmlNode {
+	for _, match := range []func(*htmlNode) bool{
+		func(n *htmlNode) bool { return n.tag == "article" },
+		func(n *htmlNode) bool { return n.tag == "main" || n.attrs["role"] == "main" },
+	} {
+		if n := root.find(match); n != nil {
+			return n
+		}
+	}
+
+	best, bestScore := root, 0
+	var score func(n *htmlNode)
+	score = func(n *htmlNode) {
+		s := 0
+		for _, c := range n.children {
+			if contentElements[c.tag] {
+				s += len(strings.TrimSpace(c.textContent()))
+			}
+			if c.tag != "" && !skippedElements[c.tag] {
+				score(c)
+			}
+		}
+		if s > bestScore {
+			best, bestScore = n, s
+		}
+	}
+	score(root)
+
+	if best == root {
+		if body := root.find(func(n *htmlNode) bool { return n.tag == "body" }); body != nil {
+			return body
+		}
+	}
+	return best
+}
+
+// htmlContainer is a block element whose lines are output differently,
+// such as a list or quote.
+type htmlContainer struct {
+	tag string
+	// Number of the next item, for ordered lists
+	number int
+}
+
+// htmlConverter converts an HTML tree to a gemtext document.
+type htmlConverter struct {
+	o   outputOptions
+	doc *Document
+	// Inline text of the current line
+	inline strings.Builder
+	// Links found since the last were output
+	links []Link
+	// Lists and quotes the current element is within
+	containers []htmlContainer
+	// Heading level of the current element, if any
+	heading int
+	// Set within links, so images within them aren't output twice
+	inLink bool
+	// Set once a block is complete, so the next is spaced from it
+	separate bool
+}
+
+// ParseHTML converts the main content of the HTML document read from
+// the supplied reader to a gemtext document. It runs entirely offline.
+//
+// The main content is taken from the document's article or main
+// element, or failing those the element with the most paragraph text;
+// navigation, forms, scripts and the like are dropped. Links and
+// images are replaced by their text, with link lines for each after the
+// block they appear in. Headings below level three are reduced to it,
+// preformatted blocks keep any language given by their class as alt
+// text, and tables become preformatted blocks. Relative links are
+// resolved against any Base URL. Text that would be read as another
+// type of line, such as a paragraph starting with "=>", or a
+// preformatted line starting with "```", is prefixed with a space.
+func ParseHTML(r io.Reader, opts ...Option) (*Document, error) {
+	var o outputOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	root, err := parseHTMLTree(r)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &htmlConverter{o: o, doc: &Document{}}
+	c.walk(mainContent(root))
+	c.endBlock()
+
+	// Title the document if its content doesn't
+	title := root.find(func(n *htmlNode) bool { return n.tag == "title" })
+	if title != nil && !hasTitle(c.doc) {
+		if text := collapseSpace(title.textContent()); text != "" {
+			c.doc.Lines = append([]Line{Heading{Level: 1, Text: text}, Text{}}, c.doc.Lines...)
+		}
+	}
+
+	return c.doc, nil
+}
+
+// hasTitle reports whether the supplied document has a level one
+// heading.
+func hasTitle(doc *Document) bool {
+	for _, line := range doc.Lines {
+		if h, ok := line.(Heading); ok && h.Level == 1 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// walk converts the supplied node and those below it.
+func (c *htmlConverter) walk(n *htmlNode) {
+	if n.tag == "" {
+		c.inline.WriteString(n.text)
+		return
+	}
+	if skippedElements[n.tag] || n.tag == "title" {
+		return
+	}
+
+	switch n.tag {
+	case "br":
+		c.flush()
+	case "hr":
+		c.endBlock()
+		c.add(Text{Text: "---"})
+		c.endBlock()
+	case "a":
+		c.link(n)
+	case "img":
+		if !c.inLink {
+			c.image(n)
+		}
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		c.endBlock()
+		c.heading = int(n.tag[1] - '0')
+		if c.heading > 3 {
+			c.heading = 3
+		}
+		c.walkChildren(n)
+		c.flush()
+		c.heading = 0
+		c.endBlock()
+	case "ul", "ol", "blockquote":
+		c.endBlock()
+		c.containers = append(c.containers, htmlContainer{tag: n.tag, number: 1})
+		c.walkChildren(n)
+		c.flush()
+		c.containers = c.containers[:len(c.containers)-1]
+		c.endBlock()
+	case "li":
+		c.flush()
+		c.walkChildren(n)
+		c.flush()
+		if len(c.containers) > 0 {
+			c.containers[len(c.containers)-1].number++
+		}
+	case "pre":
+		c.endBlock()
+		c.add(Preformatted{Alt: codeLanguage(n), Lines: preLines(n)})
+		c.endBlock()
+	case "table":
+		if isLayoutTable(n) {
+			c.walkChildren(n)
+			break
+		}
+		c.endBlock()
+		c.table(n)
+		c.endBlock()
+	default:
+		if blockElements[n.tag] {
+			c.flush()
+			c.walkChildren(n)
+			c.flush()
+			c.endBlock()
+			break
+		}
+		c.walkChildren(n)
+	}
+}
+
+// walkChildren converts the children of the supplied node.
+func (c *htmlConverter) walkChildren(n *htmlNode) {
+	for _, child := range n.children {
+		c.walk(child)
+	}
+}
+
+// link converts an anchor, keeping its text inline and recording its
+// URL, labelled with the anchor's text or failing that the alt text of
+// an image within it. In-page and script links are dropped.
+func (c *htmlConverter) link(n *htmlNode) {
+	c.inLink = true
+	c.walkChildren(n)
+	c.inLink = false
+
+	href := strings.TrimSpace(n.attrs["href"])
+	if href == "" || strings.HasPrefix(href, "#") || safeURL(href) == "#" {
+		return
+	}
+
+	label := collapseSpace(n.textContent())
+	if label == "" {
+		if img := n.find(func(c *htmlNode) bool { return c.tag == "img" }); img != nil {
+			label = collapseSpace(img.attrs["alt"])
+		}
+	}
+	c.addLink(href, label)
+}
+
+// image records a link to the supplied image, labelled with its alt
+// text.
+func (c *htmlConverter) image(n *htmlNode) {
+	src := strings.TrimSpace(n.attrs["src"])
+	if src == "" || safeURL(src) == "#" {
+		return
+	}
+	c.addLink(src, collapseSpace(n.attrs["alt"]))
+}
+
+// addLink records a link to be output after the current block.
+func (c *htmlConverter) addLink(href, label string) {
+	url := resolve(c.o.base, href)
+	if label == url {
+		label = ""
+	}
+	c.links = append(c.links, Link{URL: url, Label: label})
+}
+
+// table converts a data table into a preformatted block with aligned
+// columns.
+func (c *htmlConverter) table(n *htmlNode) {
+	var rows [][]string
+	columns := 0
+
+	var findRows func(n *htmlNode)
+	findRows = func(n *htmlNode) {
+		for _, child := range n.children {
+			if child.tag != "tr" {
+				findRows(child)
+				continue
+			}
+			var row []string
+			for _, cell := range child.children {
+				if cell.tag != "td" && cell.tag != "th" {
+					continue
+				}
+				// Convert each cell on its own, to gather its
+				// text and links
+				cc := &htmlConverter{o: c.o, doc: &Document{}}
+				cc.walkChildren(cell)
+				row = append(row, collapseSpace(cc.inline.String()))
+				c.links = append(c.links, cc.links...)
+			}
+			if len(row) > columns {
+				columns = len(row)
+			}
+			rows = append(rows, row)
+		}
+	}
+	findRows(n)
+	if len(rows) == 0 {
+		return
+	}
+
+	aligns := make([]string, columns)
+	for i := range rows {
+		for len(rows[i]) < columns {
+			rows[i] = append(rows[i], "")
+		}
+	}
+	c.add(Preformatted{Alt: "table", Lines: formatTable(rows, aligns)})
+}
+
+// flush outputs the current line of inline text, if any, as the type
+// of line the current element calls for.
+func (c *htmlConverter) flush() {
+	text := collapseSpace(c.inline.String())
+	c.inline.Reset()
+	if text == "" {
+		return
+	}
+
+	switch {
+	case c.heading > 0:
+		c.add(Heading{Level: c.heading, Text: text})
+	case len(c.containers) == 0:
+		c.add(Text{Text: text})
+	case c.inContainer("blockquote"):
+		c.add(Quote{Text: " " + text})
+	case c.containers[len(c.containers)-1].tag == "ol":
+		c.add(Text{Text: fmt.Sprintf("%d. %s", c.containers[len(c.containers)-1].number, text)})
+	default:
+		c.add(ListItem{Text: text})
+	}
+}
+
+// inContainer reports whether the current element is within the named
+// container element.
+func (c *htmlConverter) inContainer(tag string) bool {
+	for _, container := range c.containers {
+		if container.tag == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// endBlock ends the current block, outputting its links. Blocks within
+// lists and quotes are ended along with them.
+func (c *htmlConverter) endBlock() {
+	c.flush()
+	if len(c.containers) > 0 {
+		return
+	}
+
+	for _, l := range c.links {
+		c.add(l)
+	}
+	c.links = nil
+	c.separate = true
+}
+
+// add outputs a line, separating it from any previous block with a
+// blank line, and escaped so it's read back as the same line.
+func (c *htmlConverter) add(line Line) {
+	if c.separate && len(c.doc.Lines) > 0 {
+		c.doc.Lines = append(c.doc.Lines, Text{})
+	}
+	c.separate = false
+	c.doc.Lines = append(c.doc.Lines, escapeLine(line))
+}
+
+// codeLanguage returns the language of a preformatted element, given
+// by a "language-" or "lang-" class on it or a code element within it.
+func codeLanguage(n *htmlNode) string {
+	for _, el := range []*htmlNode{n, n.find(func(c *htmlNode) bool { return c.tag == "code" })} {
+		if el == nil {
+			continue
+		}
+		if lang := el.attrs["data-lang"]; lang != "" {
+			return lang
+		}
+		for _, class := range strings.Fields(el.attrs["class"]) {
+			for _, prefix := range []string{"language-", "lang-"} {
+				if strings.HasPrefix(class, prefix) {
+					return class[len(prefix):]
+				}
+			}
+		}
+	}
+
+	return ""
+}
+
+// preLines returns the lines of text within a preformatted element.
+func preLines(n *htmlNode) []string {
+	text := strings.TrimPrefix(n.textContent(), "\n")
+	text = strings.TrimRight(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+
+	return strings.Split(text, "\n")
+}
+
+// isLayoutTable reports whether a table is used for page layout rather
+// than data, holding blocks or other tables.
+func isLayoutTable(n *htmlNode) bool {
+	return n.find(func(c *htmlNode) bool {
+		switch c.tag {
+		case "table", "p", "div", "ul", "ol", "pre", "h1", "h2", "h3", "h4", "h5", "h6", "blockquote":
+			return true
+		}
+		return false
+	}) != nil
+}
+
+// collapseSpace trims s and collapses each run of whitespace within it
+// to a single space.
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,190 @@
+package gemtext
+
+import (
+	"bytes"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseHTML(t *testing.T) {
+	base, err := url.Parse("gemini://some.url/dir/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := map[string]struct {
+		input    string
+		opts     []Option
+		expected string
+	}{
+		"paragraphs": {
+			input:    "<p>One\n  two</p><p>Three<br>Four</p>",
+			expected: "One two\n\nThree\nFour\n",
+		},
+		"lenient text": {
+			input:    "<P CLASS=x>1 < 2 & 3 &copy; &hellip;</P>",
+			expected: "1 < 2 & 3 © …\n",
+		},
+		"unclosed elements": {
+			input:    "<p>One<p>Two<ul><li>A<li>B</ul><table><tr><td>1<td>2<tr><td>3<td>4</table>",
+			expected: "One\n\nTwo\n\n* A\n* B\n\n```table\n1 | 2\n--+--\n3 | 4\n```\n",
+		},
+		"unquoted attributes": {
+			input:    "<p><a href=/about>About</a> <a href=about.html>x</a> <img src=pic.png alt=x></p>",
+			expected: "About x\n=> /about About\n=> about.html x\n=> pic.png x\n",
+		},
+		"void elements": {
+			input:    "<p>One<br>Two<hr><img src=\"a.png\"><input type=text>Three</p>",
+			expected: "One\nTwo\n\n---\n\nThree\n=> a.png\n",
+		},
+		"unclosed paragraphs and list items": {
+			input:    "<p>One\n<p>Two\n<ul>\n<li>A\n<li>B\n</ul>\n<p>Three",
+			expected: "One\n\nTwo\n\n* A\n* B\n\nThree\n",
+		},
+		"line break in link": {
+			input:    "<p>Before<br>x <a href=\"/x\">after<br>break</a></p>",
+			expected: "Before\nx after\nbreak\n=> /x after break\n",
+		},
+		"links": {
+			input:    "<p>A <a href=\"/a\">link</a>, <a href=\"#top\">an anchor</a> and <a href=\"javascript:x()\">script</a>.</p>",
+			expected: "A link, an anchor and script.\n=> /a link\n",
+		},
+		"links resolved against base": {
+			input:    "<p><a href=\"../a.gmi\">A</a></p>",
+			opts:     []Option{Base(base)},
+			expected: "A\n=> gemini://some.url/a.gmi A\n",
+		},
+		"images": {
+			input:    "<p><img src=\"a.png\" alt=\"An image\"></p><a href=\"big.png\"><img src=\"small.png\" alt=\"Thumbnail\"></a>",
+			expected: "=> a.png An image\n\n=> big.png Thumbnail\n",
+		},
+		"headings": {
+			input:    "<h1>One</h1><h2>Two</h2><h3>Three</h3><h4>Four</h4><h6>Six</h6>",
+			expected: "# One\n\n## Two\n\n### Three\n\n### Four\n\n### Six\n",
+		},
+		"title": {
+			input:    "<html><head><title>Page Title</title></head><body><p>Text</p></body></html>",
+			expected: "# Page Title\n\nText\n",
+		},
+		"preformatted": {
+			input:    "<pre class=\"lang-sh\">\nls -l\n  &lt;dir&gt;\n</pre><pre><code class=\"hljs language-go\">go run .</code></pre>",
+			expected: "```sh\nls -l\n  <dir>\n```\n\n```go\ngo run .\n```\n",
+		},
+		"lists and quotes": {
+			input:    "<ol><li>One<li>Two <a href=\"/t\">link</a></ol><blockquote><p>Quote</p><p>More</p></blockquote>",
+			expected: "1. One\n2. Two link\n=> /t link\n\n> Quote\n> More\n",
+		},
+		"boilerplate dropped": {
+			input: "<nav><a href=\"/\">Home</a></nav><script>if (a < b) {}</script><style>p > a {}</style>" +
+				"<!-- <p>comment</p> --><div><p>Content</p><footer>Footer</footer></div>",
+			expected: "Content\n",
+		},
+		"main content": {
+			input:    "<div><p>Short</p></div><div id=\"content\"><p>A much longer paragraph of text.</p><p>And another.</p></div>",
+			expected: "A much longer paragraph of text.\n\nAnd another.\n",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			doc, err := ParseHTML(strings.NewReader(tc.input), tc.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+			if got := doc.String(); got != tc.expected {
+				t.Errorf("got '%s', want '%s'", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestParseHTMLReparse(t *testing.T) {
+	testCases := map[string]struct {
+		input    string
+		expected []Line
+	}{
+		"heading marker": {
+			input:    "<p># Not a heading</p>",
+			expected: []Line{Text{Text: " # Not a heading"}},
+		},
+		"link marker": {
+			input:    "<p>=&gt; not a link</p>",
+			expected: []Line{Text{Text: " => not a link"}},
+		},
+		"quote marker": {
+			input:    "<p>&gt; not a quote</p>",
+			expected: []Line{Text{Text: " > not a quote"}},
+		},
+		"list marker": {
+			input:    "<p>* not a list item</p>",
+			expected: []Line{Text{Text: " * not a list item"}},
+		},
+		"toggle in paragraph": {
+			input:    "<p>``` not a toggle</p>",
+			expected: []Line{Text{Text: " ``` not a toggle"}},
+		},
+		"ordered list item": {
+			input:    "<ol><li>=&gt; not a link</li></ol>",
+			expected: []Line{Text{Text: "1. => not a link"}},
+		},
+		"toggle in preformatted": {
+			input:    "<pre>```go\ncode\n```</pre><p>Text</p>",
+			expected: []Line{Preformatted{Lines: []string{" ```go", "code", " ```"}}, Text{}, Text{Text: "Text"}},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			doc, err := ParseHTML(strings.NewReader(tc.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+			got, err := Parse(strings.NewReader(doc.String()))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(got.Lines) != len(tc.expected) {
+				t.Fatalf("got %d lines, want %d, from '%s'", len(got.Lines), len(tc.expected), doc.String())
+			}
+			for i, line := range got.Lines {
+				if got := withoutSource(line); !reflect.DeepEqual(got, tc.expected[i]) {
+					t.Errorf("line %d: got %#v, want %#v", i, got, tc.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseHTMLArticle(t *testing.T) {
+	input, err := os.Open(filepath.Join("testdata", "article.html"))
+	if err != nil {
+		t.Fatalf("error reading test input file: %s", err)
+	}
+	defer input.Close()
+
+	doc, err := ParseHTML(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+	got := []byte(doc.String())
+
+	golden := filepath.Join("testdata", "article.gmi")
+	if *update {
+		if err := os.WriteFile(golden, got, 0644); err != nil {
+			t.Fatalf("error updating golden file '%s': %s", golden, err)
+		}
+	}
+
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("error reading test golden file: %s", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("got '%s', want '%s'", got, want)
+	}
+}
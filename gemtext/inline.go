@@ -0,0 +1,339 @@
+package gemtext
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	// entityRef matches an HTML character reference
+	entityRef = regexp.MustCompile(`^&(#[0-9]{1,7}|#[xX][0-9a-fA-F]{1,6}|[a-zA-Z][a-zA-Z0-9]{1,31});`)
+	// autolink matches a Markdown autolink, e.g. <https://some.url/>
+	autolink = regexp.MustCompile(`^<([a-zA-Z][a-zA-Z0-9+.-]{1,31}:[^<>\s]*)>`)
+	// inlineTag matches an inline HTML tag or comment
+	inlineTag = regexp.MustCompile(`^(</?[a-zA-Z][a-zA-Z0-9-]*(\s[^<>]*)?/?>|<!--.*?-->)`)
+)
+
+// inlineDelim is a run of emphasis delimiters found in Markdown
+// inline text.
+type inlineDelim struct {
+	// Index of the run's piece of text
+	piece    int
+	char     rune
+	canOpen  bool
+	canClose bool
+}
+
+// flattenInline converts Markdown inline text to plain text: escapes
+// and character references are decoded, emphasis and inline HTML tags
+// are removed, and code spans keep just their content. Links and
+// images are replaced by their text, and returned separately.
+func flattenInline(s string, refs map[string]Link) (string, []Link) {
+	var pieces []string
+	var delims []inlineDelim
+	var links []Link
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		rest := s[i:]
+
+		switch {
+		case c == '\\' && i+1 < len(s) && isASCIIPunct(s[i+1]):
+			pieces = append(pieces, s[i+1:i+2])
+			i += 2
+			continue
+		case c == '`':
+			if code, n := codeSpan(rest); n > 0 {
+				pieces = append(pieces, code)
+				i += n
+				continue
+			}
+			// An unmatched run of backticks is literal
+			n := len(rest) - len(strings.TrimLeft(rest, "`"))
+			pieces = append(pieces, rest[:n])
+			i += n
+			continue
+		case c == '[' || c == '!' && strings.HasPrefix(rest, "!["):
+			offset := 0
+			if c == '!' {
+				offset = 1
+			}
+			if l, n := parseInlineLink(rest[offset:], refs); n > 0 {
+				label, inner := flattenInline(l.Label, refs)
+				links = append(links, inner...)
+				pieces = append(pieces, label)
+				links = append(links, Link{URL: l.URL, Label: label})
+				i += offset + n
+				continue
+			}
+		case c == '<':
+			if m := autolink.FindStringSubmatch(rest); m != nil {
+				pieces = append(pieces, m[1])
+				links = append(links, Link{URL: m[1]})
+				i += len(m[0])
+				continue
+			}
+			if m := inlineTag.FindString(rest); m != "" {
+				i += len(m)
+				continue
+			}
+		case c == '&':
+			if m := entityRef.FindString(rest); m != "" {
+				pieces = append(pieces, html.UnescapeString(m))
+				i += len(m)
+				continue
+			}
+		case c == '*' || c == '_' || c == '~':
+			n := len(rest) - len(strings.TrimLeft(rest, string(c)))
+			// Single tildes are literal
+			if c != '~' || n >= 2 {
+				before, _ := utf8.DecodeLastRuneInString(s[:i])
+				after, _ := utf8.DecodeRuneInString(s[i+n:])
+				delims = append(delims, newDelim(len(pieces), rune(c), before, after, i == 0, i+n == len(s)))
+			}
+			pieces = append(pieces, rest[:n])
+			i += n
+			continue
+		}
+
+		_, size := utf8.DecodeRuneInString(rest)
+		pieces = append(pieces, rest[:size])
+		i += size
+	}
+
+	// Drop emphasis delimiters that pair up; the rest are literal
+	for i, closer := range delims {
+		if !closer.canClose {
+			continue
+		}
+		for j := i - 1; j >= 0; j-- {
+			opener := delims[j]
+			if opener.char != closer.char || !opener.canOpen || pieces[opener.piece] == "" {
+				continue
+			}
+			pieces[opener.piece] = ""
+			pieces[closer.piece] = ""
+			delims[j].canOpen = false
+			delims[i].canOpen = false
+			break
+		}
+	}
+
+	return strings.Join(pieces, ""), links
+}
+
+// newDelim creates an emphasis delimiter run of char, which could open
+// emphasis if followed by non-space, and close it if preceded by it.
+// Underscores within words are not delimiters.
+func newDelim(piece int, char, before, after rune, atStart, atEnd bool) inlineDelim {
+	d := inlineDelim{
+		piece:    piece,
+		char:     char,
+		canOpen:  !atEnd && !unicode.IsSpace(after),
+		canClose: !atStart && !unicode.IsSpace(before),
+	}
+	if char == '_' {
+		d.canOpen = d.canOpen && (atStart || !isAlnum(before))
+		d.canClose = d.canClose && (atEnd || !isAlnum(after))
+	}
+
+	return d
+}
+
+// codeSpan parses a code span at the start of s, returning its content
+// and length, or a length of zero if there isn't one.
+func codeSpan(s string) (string, int) {
+	n := len(s) - len(strings.TrimLeft(s, "`"))
+	fence := s[:n]
+
+	for i := n; i < len(s); {
+		j := strings.Index(s[i:], fence)
+		if j < 0 {
+			return "", 0
+		}
+		j += i
+		// The closing run must be exactly as long as the opening one
+		end := j + n
+		if end < len(s) && s[end] == '`' {
+			i = end + len(s[end:]) - len(strings.TrimLeft(s[end:], "`"))
+			continue
+		}
+
+		code := s[n:j]
+		if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.TrimSpace(code) != "" {
+			code = code[1 : len(code)-1]
+		}
+		return code, end
+	}
+
+	return "", 0
+}
+
+// parseInlineLink parses a link at the start of s, in any of the forms
+// [label](url "title"), [label][ref], [label][] or [label]. It returns
+// the link, with its label unflattened, and its length, or a length of
+// zero if there isn't one.
+func parseInlineLink(s string, refs map[string]Link) (Link, int) {
+	end := closingBracket(s)
+	if end < 0 {
+		return Link{}, 0
+	}
+	label := s[1:end]
+	rest := s[end+1:]
+
+	if strings.HasPrefix(rest, "(") {
+		if url, n := linkDestination(rest); n > 0 {
+			return Link{URL: url, Label: label}, end + 1 + n
+		}
+	}
+
+	ref, n := label, 0
+	if strings.HasPrefix(rest, "[") {
+		if refEnd := closingBracket(rest); refEnd >= 0 {
+			if refEnd > 1 {
+				ref = rest[1:refEnd]
+			}
+			n = refEnd + 1
+		}
+	}
+	if l, ok := refs[normaliseRef(ref)]; ok {
+		return Link{URL: l.URL, Label: label}, end + 1 + n
+	}
+
+	return Link{}, 0
+}
+
+// closingBracket returns the index of the bracket closing the one at
+// the start of s, or -1 if it isn't closed.
+func closingBracket(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+// linkDestination parses the parenthesised destination and optional
+// title of an inline link at the start of s, returning the URL and the
+// length parsed, or a length of zero if it isn't valid.
+func linkDestination(s string) (string, int) {
+	i := 1
+	for i < len(s) && s[i] == ' ' {
+		i++
+	}
+
+	var url string
+	if i < len(s) && s[i] == '<' {
+		end := strings.IndexByte(s[i:], '>')
+		if end < 0 {
+			return "", 0
+		}
+		url = s[i+1 : i+end]
+		i += end + 1
+	} else {
+		start := i
+		depth := 0
+		for ; i < len(s); i++ {
+			c := s[i]
+			if c == '\\' && i+1 < len(s) {
+				i++
+				continue
+			}
+			if c == ' ' || c == ')' && depth == 0 {
+				break
+			}
+			if c == '(' {
+				depth++
+			} else if c == ')' {
+				depth--
+			}
+		}
+		url = unescapePunct(s[start:i])
+	}
+
+	// Skip any title
+	rest := strings.TrimLeft(s[i:], " ")
+	if len(rest) > 0 && strings.ContainsRune(`"'(`, rune(rest[0])) {
+		closer := rest[0]
+		if closer == '(' {
+			closer = ')'
+		}
+		end := strings.IndexByte(rest[1:], closer)
+		if end < 0 {
+			return "", 0
+		}
+		rest = strings.TrimLeft(rest[end+2:], " ")
+	}
+	if !strings.HasPrefix(rest, ")") {
+		return "", 0
+	}
+
+	return url, len(s) - len(rest) + 1
+}
+
+// normaliseRef normalises a link reference label for matching, as
+// labels are case-insensitive and collapse whitespace.
+func normaliseRef(ref string) string {
+	return strings.ToLower(strings.Join(strings.Fields(ref), " "))
+}
+
+// unescapePunct removes backslash escapes from ASCII punctuation.
+func unescapePunct(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && isASCIIPunct(s[i+1]) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+
+	return b.String()
+}
+
+// escapeInline escapes the characters in s that Markdown would
+// otherwise take as inline markup.
+func escapeInline(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		before, _ := utf8.DecodeLastRuneInString(s[:i])
+		after, _ := utf8.DecodeRuneInString(s[i+utf8.RuneLen(r):])
+
+		switch {
+		case strings.ContainsRune("\\`*[]<", r),
+			// Underscores within words aren't emphasis
+			r == '_' && (!isAlnum(before) || !isAlnum(after)),
+			// Nor are single tildes strikethrough
+			r == '~' && (before == '~' || after == '~'),
+			r == '&' && entityRef.MatchString(s[i:]):
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// isASCIIPunct reports whether c is an ASCII punctuation character,
+// which may be backslash-escaped in Markdown.
+func isASCIIPunct(c byte) bool {
+	return c < utf8.RuneSelf && unicode.IsPunct(rune(c)) || strings.IndexByte("$+<=>^`|~", c) >= 0
+}
+
+// isAlnum reports whether r is a letter or digit.
+func isAlnum(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,79 @@
+package gemtext
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Punctuation that may not start a line (closing brackets, full stops,
+// small kana and the like), and punctuation that may not end one
+// (opening brackets); a simplified form of the UAX #14 CL, CP, EX, IS,
+// NS and OP classes.
+const (
+	noBreakBefore = "!),.:;?]}…‥、。，．：；？！・ー‐～）」』】〕〉》〙〗〟｝ゝゞヽヾ々〻" +
+		"ぁぃぅぇぉっゃゅょゎゕゖァィゥェォッャュョヮヵヶ’”"
+	noBreakAfter = "([{‘“（「『【〔〈《〘〖〝｛"
+)
+
+// segment is a run of text that must not be broken across lines.
+type segment struct {
+	text string
+	// Set if the segment is separated from the previous one by
+	// whitespace, which is dropped if a line breaks between them
+	space bool
+}
+
+// segments splits the supplied line into segments at its line break
+// opportunities. These follow a simplified form of the Unicode line
+// breaking algorithm (UAX #14): lines may break at whitespace, and
+// either side of ideographic characters, allowing text in scripts
+// written without spaces (Chinese, Japanese, Korean) to be wrapped,
+// except where that would leave closing punctuation at the start of
+// a line or opening punctuation at the end of one.
+//
+// Scripts needing dictionary-based breaking (Thai, Lao, Khmer, etc.)
+// are only broken at whitespace.
+func segments(line string) []segment {
+	var segs []segment
+
+	for _, word := range strings.Fields(line) {
+		start := 0
+		prev := rune(-1)
+		for i, r := range word {
+			if prev >= 0 && canBreakBetween(prev, r) {
+				segs = append(segs, segment{text: word[start:i], space: start == 0})
+				start = i
+			}
+			prev = r
+		}
+		segs = append(segs, segment{text: word[start:], space: start == 0})
+	}
+
+	return segs
+}
+
+// canBreakBetween reports whether a line may break between the two
+// supplied adjacent, non-whitespace runes.
+func canBreakBetween(before, after rune) bool {
+	switch {
+	case strings.ContainsRune(noBreakAfter, before),
+		strings.ContainsRune(noBreakBefore, after),
+		// Combining marks and joined characters stay with
+		// what they follow
+		before == zeroWidthJoiner,
+		runeWidth(after) == 0,
+		after >= emojiModifierFirst && after <= emojiModifierLast:
+		return false
+	}
+
+	return isIdeographic(before) || isIdeographic(after)
+}
+
+// isIdeographic reports whether the supplied rune is an ideographic
+// character (UAX #14 class ID or a Hangul syllable), which lines may
+// break either side of.
+func isIdeographic(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) ||
+		// Wide emoji are also treated as ideographs
+		unicode.Is(wide, r) && unicode.Is(unicode.So, r)
+}
@@ -0,0 +1,274 @@
+package gemtext
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// externalMarker is appended to links leaving the capsule being output,
+// when marked with MarkExternal.
+const externalMarker = "↗"
+
+// defaultPort is the port Gemini URLs use when none is given.
+const defaultPort = "1965"
+
+// References determines where a list of numbered link references is
+// output.
+type References int
+
+const (
+	// ReferencesNone outputs no reference list
+	ReferencesNone References = iota
+	// ReferencesEnd outputs a reference list at the end of the page
+	ReferencesEnd
+	// ReferencesSection outputs a reference list at the end of each
+	// section, before the next heading
+	ReferencesSection
+)
+
+var referencesNames = map[string]References{
+	"none":    ReferencesNone,
+	"end":     ReferencesEnd,
+	"section": ReferencesSection,
+}
+
+// ParseReferences returns the reference list placement with the
+// supplied name; one of "none", "end" or "section".
+func ParseReferences(name string) (References, error) {
+	refs, ok := referencesNames[name]
+	if !ok {
+		return ReferencesNone, fmt.Errorf("unknown reference list placement '%s'", name)
+	}
+
+	return refs, nil
+}
+
+// NumberLinks sets whether links are output numbered by their position
+// in the page, as "[3] label", rather than inline as "label [url]".
+func NumberLinks(number bool) Option {
+	return func(o *outputOptions) {
+		o.numberLinks = number
+	}
+}
+
+// LinkReferences sets where a list of numbered link URLs is output.
+// Outputting references implies numbering links.
+func LinkReferences(refs References) Option {
+	return func(o *outputOptions) {
+		o.references = refs
+	}
+}
+
+// Base sets the URL of the page being output, which relative link URLs
+// are resolved against; links are also normalised. For a fetched page,
+// pass the URL of its gemini.Response, e.g. Base(&resp.URL).
+func Base(base *url.URL) Option {
+	return func(o *outputOptions) {
+		o.base = base
+	}
+}
+
+// MarkExternal sets whether links to other capsules - those with a
+// different scheme or host to the Base URL - are marked with an arrow.
+// It has no effect without a Base URL.
+func MarkExternal(mark bool) Option {
+	return func(o *outputOptions) {
+		o.markExternal = mark
+	}
+}
+
+// PageLink is a link extracted from a page, with details of where on
+// the page it appears.
+type PageLink struct {
+	URL   string
+	Label string
+	// Line number of the link, from 1
+	Line int
+	// Text of the heading the link sits under, if any
+	Heading string
+	// Position of the link within its list - the run of consecutive
+	// link lines it is part of - from 1
+	Position int
+	// Set if the link's URL was relative as written, before any
+	// resolution against a Base URL
+	Relative bool
+	// Set if the link leaves the capsule of the Base URL, having a
+	// different scheme or host; never set without a Base URL
+	External bool
+}
+
+// ExtractPageLinks returns the links in the provided io.Reader, in
+// the order they appear. Duplicate URLs are kept, each with its own
+// label. Given a Base URL, relative links are resolved against it, and
+// links to other capsules are flagged as External. Labels are returned
+// as written, whatever MarkExternal is set to.
+func ExtractPageLinks(r io.Reader, opts ...Option) ([]PageLink, error) {
+	var o outputOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var links []PageLink
+	var heading string
+	position := 0
+
+	p := NewParser(r)
+	for {
+		line, err := p.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading links: %w", err)
+		}
+
+		switch l := line.(type) {
+		case Heading:
+			heading = l.Text
+		case Link:
+			position++
+			url := resolve(o.base, l.URL)
+			// Links are single lines, so the link's is the last read
+			links = append(links, PageLink{
+				URL:      url,
+				Label:    l.Label,
+				Line:     p.lines,
+				Heading:  heading,
+				Position: position,
+				Relative: isRelative(l.URL),
+				External: isExternal(o.base, url),
+			})
+			continue
+		}
+		position = 0
+	}
+
+	return links, nil
+}
+
+// linkRefs tracks the links output so far, for numbering and listing
+// as references.
+type linkRefs struct {
+	count int
+	// URLs of links not yet output in a reference list
+	pending []string
+	// Number of the first pending link
+	first int
+}
+
+// add records a link, returning its formatted, numbered text.
+func (r *linkRefs) add(url, label string) string {
+	r.count++
+	if len(r.pending) == 0 {
+		r.first = r.count
+	}
+	r.pending = append(r.pending, url)
+
+	if label == "" {
+		label = url
+	}
+	return fmt.Sprintf("[%d] %s", r.count, label)
+}
+
+// flush returns a block listing the URLs of links recorded since the
+// last flush. It returns an empty block if there are none.
+func (r *linkRefs) flush() block {
+	b := block{lineType: link}
+	for i, url := range r.pending {
+		b.lines = append(b.lines, fmt.Sprintf("[%d] %s", r.first+i, url))
+	}
+	r.pending = nil
+
+	return b
+}
+
+// link returns the formatted text of the supplied link, numbering it
+// if required.
+func (o outputOptions) link(refs *linkRefs, url, label string) string {
+	url = resolve(o.base, url)
+	external := o.markExternal && isExternal(o.base, url)
+
+	styledURL := o.inlineStyle(url, o.theme.LinkURL, o.theme.Link)
+	if label != "" {
+		label = o.inlineStyle(label, o.theme.LinkLabel, o.theme.Link)
+	}
+
+	var s string
+	if o.numberLinks || o.references != ReferencesNone {
+		s = refs.add(styledURL, label)
+	} else {
+		s = formatLink(styledURL, label)
+	}
+
+	if external {
+		s += " " + externalMarker
+	}
+	return s
+}
+
+// resolve resolves the supplied link URL against base as per RFC 3986,
+// and normalises the result. It returns the link unchanged if there's
+// no base or it can't be parsed.
+func resolve(base *url.URL, link string) string {
+	if base == nil {
+		return link
+	}
+
+	ref, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+
+	return normalise(base.ResolveReference(ref)).String()
+}
+
+// normalise returns a normalised copy of the supplied absolute URL, so
+// that equivalent URLs compare equal: the host is lowercased, the
+// default Gemini port is dropped, and an empty path becomes "/". The
+// scheme is already lowercased by url.Parse, and dot segments are
+// removed by URL.ResolveReference.
+func normalise(u *url.URL) *url.URL {
+	n := *u
+	n.Host = strings.ToLower(n.Host)
+	if n.Scheme == "gemini" && n.Port() == defaultPort {
+		n.Host = n.Hostname()
+		// Restore the brackets around IPv6 addresses
+		if strings.Contains(n.Host, ":") {
+			n.Host = "[" + n.Host + "]"
+		}
+	}
+	if n.Host != "" && n.Path == "" && n.Opaque == "" {
+		n.Path = "/"
+	}
+
+	return &n
+}
+
+// isRelative reports whether the supplied link URL is a relative
+// reference, lacking a scheme.
+func isRelative(link string) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+
+	return !u.IsAbs()
+}
+
+// isExternal reports whether the supplied resolved link URL leaves the
+// capsule of the base URL. Without a base, no link is external.
+func isExternal(base *url.URL, link string) bool {
+	if base == nil {
+		return false
+	}
+
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+
+	return u.Scheme != base.Scheme || u.Hostname() != strings.ToLower(base.Hostname())
+}
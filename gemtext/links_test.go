@@ -0,0 +1,196 @@
+package gemtext
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseReferences(t *testing.T) {
+	for name, want := range referencesNames {
+		got, err := ParseReferences(name)
+		if err != nil {
+			t.Errorf("unexpected error: %q", err)
+		}
+		if got != want {
+			t.Errorf("got references %d for '%s', want %d", got, name, want)
+		}
+	}
+
+	if _, err := ParseReferences("invalid"); err == nil {
+		t.Error("expected an error, but got nil")
+	}
+}
+
+func TestResolve(t *testing.T) {
+	base, err := url.Parse("gemini://Some.URL/dir/page.gmi")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := map[string]struct {
+		link     string
+		expected string
+	}{
+		"absolute":            {link: "gemini://other.url/page.gmi", expected: "gemini://other.url/page.gmi"},
+		"absolute path":       {link: "/a/b.gmi", expected: "gemini://some.url/a/b.gmi"},
+		"relative path":       {link: "b.gmi", expected: "gemini://some.url/dir/b.gmi"},
+		"parent path":         {link: "../foo.gmi", expected: "gemini://some.url/foo.gmi"},
+		"dot segments":        {link: "./a/../b/./c.gmi", expected: "gemini://some.url/dir/b/c.gmi"},
+		"query":               {link: "?search", expected: "gemini://some.url/dir/page.gmi?search"},
+		"network path":        {link: "//other.url/", expected: "gemini://other.url/"},
+		"uppercase host":      {link: "gemini://OTHER.url/", expected: "gemini://other.url/"},
+		"uppercase scheme":    {link: "GEMINI://other.url/", expected: "gemini://other.url/"},
+		"default port":        {link: "gemini://other.url:1965/a", expected: "gemini://other.url/a"},
+		"other port":          {link: "gemini://other.url:1966/a", expected: "gemini://other.url:1966/a"},
+		"empty path":          {link: "gemini://other.url", expected: "gemini://other.url/"},
+		"ipv6 default port":   {link: "gemini://[::1]:1965/", expected: "gemini://[::1]/"},
+		"other scheme":        {link: "https://other.url:1965", expected: "https://other.url:1965/"},
+		"opaque":              {link: "mailto:someone@some.url", expected: "mailto:someone@some.url"},
+		"unparseable is kept": {link: "%zz", expected: "%zz"},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if got := resolve(base, tc.link); got != tc.expected {
+				t.Errorf("got '%s', want '%s'", got, tc.expected)
+			}
+		})
+	}
+
+	if got := resolve(nil, "../foo.gmi"); got != "../foo.gmi" {
+		t.Errorf("got '%s' without a base, want link unchanged", got)
+	}
+}
+
+func TestIsExternal(t *testing.T) {
+	base, err := url.Parse("gemini://Some.URL/dir/page.gmi")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := map[string]struct {
+		link     string
+		expected bool
+	}{
+		"same capsule":   {link: "gemini://some.url/other.gmi", expected: false},
+		"other capsule":  {link: "gemini://other.url/", expected: true},
+		"other scheme":   {link: "https://some.url/", expected: true},
+		"other port":     {link: "gemini://some.url:1966/", expected: false},
+		"non-hierarchic": {link: "mailto:someone@some.url", expected: true},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if got := isExternal(base, tc.link); got != tc.expected {
+				t.Errorf("got %t, want %t", got, tc.expected)
+			}
+		})
+	}
+
+	if isExternal(nil, "gemini://other.url/") {
+		t.Error("got external link without a base")
+	}
+}
+
+func TestExtractLinksBase(t *testing.T) {
+	input := "=> ../foo.gmi Foo\n=> gemini://other.url Other\n=> gopher://some.url/\n"
+	base, err := url.Parse("gemini://some.url/dir/page.gmi")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"gemini://some.url/foo.gmi": "Foo",
+		"gemini://other.url/":       "Other ↗",
+		"gopher://some.url/":        "↗",
+	}
+
+	got := ExtractLinks(strings.NewReader(input), Base(base), MarkExternal(true))
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %v, want %v", got, expected)
+	}
+}
+
+func TestExtractPageLinks(t *testing.T) {
+	base, err := url.Parse("gemini://some.url/dir/page.gmi")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := map[string]struct {
+		input    string
+		opts     []Option
+		expected []PageLink
+	}{
+		"document order": {
+			input: "=> /b B\n=> /a A\n=> /c C\n",
+			expected: []PageLink{
+				{URL: "/b", Label: "B", Line: 1, Position: 1, Relative: true},
+				{URL: "/a", Label: "A", Line: 2, Position: 2, Relative: true},
+				{URL: "/c", Label: "C", Line: 3, Position: 3, Relative: true},
+			},
+		},
+		"duplicates kept": {
+			input: "=> /a First\n=> /a Second\n",
+			expected: []PageLink{
+				{URL: "/a", Label: "First", Line: 1, Position: 1, Relative: true},
+				{URL: "/a", Label: "Second", Line: 2, Position: 2, Relative: true},
+			},
+		},
+		"headings and lists": {
+			input: "# One\n=> /a\n\n=> /b\n=> /c\n## Two\n=> /d\n",
+			expected: []PageLink{
+				{URL: "/a", Line: 2, Heading: "One", Position: 1, Relative: true},
+				{URL: "/b", Line: 4, Heading: "One", Position: 1, Relative: true},
+				{URL: "/c", Line: 5, Heading: "One", Position: 2, Relative: true},
+				{URL: "/d", Line: 7, Heading: "Two", Position: 1, Relative: true},
+			},
+		},
+		"preformatted ignored": {
+			input: "```\n=> /a\n# Not a heading\n```\n=> /b\n",
+			expected: []PageLink{
+				{URL: "/b", Line: 5, Position: 1, Relative: true},
+			},
+		},
+		"absolute and relative": {
+			input: "=> gemini://some.url/\n=> //some.url/\n",
+			expected: []PageLink{
+				{URL: "gemini://some.url/", Line: 1, Position: 1},
+				{URL: "//some.url/", Line: 2, Position: 2, Relative: true},
+			},
+		},
+		"external links": {
+			input: "=> ../foo.gmi Foo\n=> gemini://other.url Other\n=> gopher://some.url/\n",
+			opts:  []Option{Base(base), MarkExternal(true)},
+			expected: []PageLink{
+				{URL: "gemini://some.url/foo.gmi", Label: "Foo", Line: 1, Position: 1, Relative: true},
+				{URL: "gemini://other.url/", Label: "Other", Line: 2, Position: 2, External: true},
+				{URL: "gopher://some.url/", Line: 3, Position: 3, External: true},
+			},
+		},
+		"lines over 64KiB": {
+			input: strings.Repeat("x", 70000) + "\n=> /a " + strings.Repeat("A", 70000) + "\n=> /b B\n",
+			expected: []PageLink{
+				{URL: "/a", Label: strings.Repeat("A", 70000), Line: 2, Position: 1, Relative: true},
+				{URL: "/b", Label: "B", Line: 3, Position: 2, Relative: true},
+			},
+		},
+		"no links": {
+			input: "Just text\n",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			got, err := ExtractPageLinks(strings.NewReader(tc.input), tc.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("got %+v, want %+v", got, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package gemtext
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ManSection sets the manual section man pages are output in, which
+// defaults to 7 (miscellaneous).
+func ManSection(section string) Option {
+	return func(o *outputOptions) {
+		o.manSection = section
+	}
+}
+
+// manRenderer renders gemtext as a man page.
+type manRenderer struct {
+	w io.Writer
+	o outputOptions
+
+	body  strings.Builder
+	title string
+	// Set once the first heading has been seen
+	headed bool
+	quoted bool
+}
+
+// NewManRenderer returns a Renderer converting gemtext to a man page,
+// written in roff with the man macros. A level one heading before any
+// other becomes the page's title; other headings become sections and
+// subsections.
+func NewManRenderer(w io.Writer, opts ...Option) Renderer {
+	m := &manRenderer{w: w, o: outputOptions{manSection: "7"}}
+	for _, opt := range opts {
+		opt(&m.o)
+	}
+
+	return m
+}
+
+func (m *manRenderer) Start() error {
+	m.body.Reset()
+	m.title = "GEMTEXT"
+	m.headed = false
+	m.quoted = false
+	return nil
+}
+
+func (m *manRenderer) Text(line Text) error {
+	m.quote(false)
+	// Blank lines only space out the gemtext
+	if strings.TrimSpace(line.Text) != "" {
+		fmt.Fprintf(&m.body, ".PP\n%s\n", manEscape(line.Text))
+	}
+	return nil
+}
+
+func (m *manRenderer) Link(line Link) error {
+	m.quote(false)
+	fmt.Fprintf(&m.body, ".PP\n.UR %s\n", manEscape(resolve(m.o.base, line.URL)))
+	if line.Label != "" {
+		fmt.Fprintf(&m.body, "%s\n", manEscape(line.Label))
+	}
+	m.body.WriteString(".UE\n")
+	return nil
+}
+
+func (m *manRenderer) Heading(line Heading) error {
+	m.quote(false)
+	if !m.headed {
+		m.headed = true
+		if line.Level == 1 {
+			m.title = strings.ToUpper(line.Text)
+			return nil
+		}
+	}
+
+	macro := ".SH"
+	if line.Level == 3 {
+		macro = ".SS"
+	}
+	fmt.Fprintf(&m.body, "%s %s\n", macro, manArg(line.Text))
+	return nil
+}
+
+func (m *manRenderer) ListItem(line ListItem) error {
+	m.quote(false)
+	fmt.Fprintf(&m.body, ".IP \\(bu 2\n%s\n", manEscape(line.Text))
+	return nil
+}
+
+func (m *manRenderer) Quote(line Quote) error {
+	m.quote(true)
+	fmt.Fprintf(&m.body, ".PP\n%s\n", manEscape(strings.TrimSpace(line.Text)))
+	return nil
+}
+
+func (m *manRenderer) Preformatted(line Preformatted) error {
+	m.quote(false)
+	m.body.WriteString(".PP\n.RS 4\n.nf\n")
+	for _, pre := range line.Lines {
+		fmt.Fprintf(&m.body, "%s\n", manEscape(pre))
+	}
+	m.body.WriteString(".fi\n.RE\n")
+	return nil
+}
+
+func (m *manRenderer) End() error {
+	m.quote(false)
+	_, err := fmt.Fprintf(m.w, ".TH %s %s\n%s", manArg(m.title), manArg(m.o.manSection), m.body.String())
+	return err
+}
+
+// quote indents the following lines as a quote, or ends the
+// indentation of preceding quotes.
+func (m *manRenderer) quote(quoted bool) {
+	if quoted == m.quoted {
+		return
+	}
+
+	if quoted {
+		m.body.WriteString(".RS 4\n")
+	} else {
+		m.body.WriteString(".RE\n")
+	}
+	m.quoted = quoted
+}
+
+// manEscape escapes a line of text for roff: backslashes are escaped,
+// and lines that would otherwise be taken as requests are protected
+// with a zero-width character.
+func manEscape(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\e`)
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		s = `\&` + s
+	}
+
+	return s
+}
+
+// manArg quotes a macro argument for roff.
+func manArg(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\e`)
+	return `"` + strings.ReplaceAll(s, `"`, `\(dq`) + `"`
+}
@@ -0,0 +1,60 @@
+package gemtext
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestManRenderer(t *testing.T) {
+	base, err := url.Parse("gemini://some.url/docs/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := map[string]struct {
+		input    string
+		opts     []Option
+		expected string
+	}{
+		"title and sections": {
+			input:    "# gmifmt\n## Synopsis\n### Flags\n# Bugs\n",
+			expected: ".TH \"GMIFMT\" \"7\"\n.SH \"Synopsis\"\n.SS \"Flags\"\n.SH \"Bugs\"\n",
+		},
+		"no title": {
+			input:    "## \"Quoted\"\nText\n",
+			opts:     []Option{ManSection("1")},
+			expected: ".TH \"GEMTEXT\" \"1\"\n.SH \"\\(dqQuoted\\(dq\"\n.PP\nText\n",
+		},
+		"escaping": {
+			input:    ".not a request\n'nor this\nC:\\path\n",
+			expected: ".TH \"GEMTEXT\" \"7\"\n.PP\n\\&.not a request\n.PP\n\\&'nor this\n.PP\nC:\\epath\n",
+		},
+		"lists and quotes": {
+			input:    "* One\n> Quoted\n> More\nText\n",
+			expected: ".TH \"GEMTEXT\" \"7\"\n.IP \\(bu 2\nOne\n.RS 4\n.PP\nQuoted\n.PP\nMore\n.RE\n.PP\nText\n",
+		},
+		"links": {
+			input:    "=> page.gmi A page\n=> gemini://other.url/\n",
+			opts:     []Option{Base(base)},
+			expected: ".TH \"GEMTEXT\" \"7\"\n.PP\n.UR gemini://some.url/docs/page.gmi\nA page\n.UE\n.PP\n.UR gemini://other.url/\n.UE\n",
+		},
+		"preformatted": {
+			input:    "```\n.code\n```\n",
+			expected: ".TH \"GEMTEXT\" \"7\"\n.PP\n.RS 4\n.nf\n\\&.code\n.fi\n.RE\n",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var got bytes.Buffer
+			if err := Render(strings.NewReader(tc.input), NewManRenderer(&got, tc.opts...)); err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+			if got.String() != tc.expected {
+				t.Errorf("got '%s', want '%s'", got.String(), tc.expected)
+			}
+		})
+	}
+}
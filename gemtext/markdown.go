@@ -0,0 +1,664 @@
+package gemtext
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	atxHeading    = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	setextLine    = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+	thematicBreak = regexp.MustCompile(`^ {0,3}(?:(?:-[ \t]*){3,}|(?:\*[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	codeFence     = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})[ \t]*(.*)$")
+	listMarker    = regexp.MustCompile(`^[ \t]*([-*+]|\d{1,9}[.)])(?:[ \t]+(.*))?$`)
+	quoteMarker   = regexp.MustCompile(`^ {0,3}> ?`)
+	refDefinition = regexp.MustCompile(`^ {0,3}\[([^\]]+)\]:[ \t]*<?([^\s>]+)>?(?:[ \t]+(?:"[^"]*"|'[^']*'|\([^)]*\)))?[ \t]*$`)
+	tableDelim    = regexp.MustCompile(`^[ \t]*\|?[ \t]*:?-+:?[ \t]*(\|[ \t]*:?-+:?[ \t]*)*\|?[ \t]*$`)
+	orderedStart  = regexp.MustCompile(`^(\d+)([.)])`)
+)
+
+// WriteMarkdown converts the supplied gemtext document to Markdown,
+// writing it to w.
+//
+// Converting the Markdown back with ParseMarkdown gives the same
+// document, save that whitespace after the prefixes of quotes and list
+// items is normalised to a single space, link labels repeating their
+// URL are dropped, and any text after the closing toggle line of a
+// preformatted block, which clients ignore, is dropped. A preformatted
+// block left open at the end of the document is left open in the
+// Markdown too. Consecutive
+// text and link lines are ended with hard line breaks, so each stays
+// on a line of its own.
+func WriteMarkdown(w io.Writer, doc *Document) error {
+	bw := bufio.NewWriter(w)
+
+	for i, line := range doc.Lines {
+		var next Line
+		if i < len(doc.Lines)-1 {
+			next = doc.Lines[i+1]
+		}
+
+		switch l := line.(type) {
+		case Text:
+			if l.Text != "" {
+				bw.WriteString(escapeMarkdownLine(l.Text))
+				bw.WriteString(hardBreak(next))
+			}
+		case Link:
+			bw.WriteString(markdownLink(l))
+			bw.WriteString(hardBreak(next))
+		case Heading:
+			bw.WriteString(strings.Repeat("#", l.Level) + " " + escapeHeading(l.Text))
+		case ListItem:
+			bw.WriteString("* " + escapeInline(strings.TrimLeft(l.Text, " \t")))
+		case Quote:
+			text := strings.TrimLeft(l.Text, " \t")
+			if text == "" {
+				bw.WriteString(">")
+				break
+			}
+			bw.WriteString("> " + escapeInline(text))
+			if q, ok := next.(Quote); ok && strings.TrimSpace(q.Text) != "" {
+				bw.WriteString("\\")
+			}
+		case Preformatted:
+			fence := codeFenceFor(l)
+			bw.WriteString(fence + l.Alt + "\n")
+			for _, pl := range l.Lines {
+				bw.WriteString(pl + "\n")
+			}
+			if l.unterminated {
+				continue
+			}
+			bw.WriteString(fence)
+		}
+		bw.WriteString("\n")
+	}
+
+	return bw.Flush()
+}
+
+// hardBreak returns the hard line break to end a text or link line
+// with, if the supplied next line would otherwise be joined to it.
+func hardBreak(next Line) string {
+	switch l := next.(type) {
+	case Text:
+		if l.Text != "" {
+			return "\\"
+		}
+	case Link:
+		return "\\"
+	}
+
+	return ""
+}
+
+// markdownLink formats the supplied link line as a Markdown link.
+func markdownLink(l Link) string {
+	label := l.Label
+	if label == "" {
+		label = l.URL
+	}
+
+	url := l.URL
+	if strings.ContainsAny(url, "()<> \\") {
+		url = "<" + strings.NewReplacer("<", "%3C", ">", "%3E").Replace(url) + ">"
+	}
+
+	return "[" + escapeInline(label) + "](" + url + ")"
+}
+
+// escapeMarkdownLine escapes a text line, so Markdown takes it as
+// plain text rather than as markup. As well as inline markup, this
+// covers characters that would start a block, and leading and trailing
+// whitespace, which Markdown would otherwise strip.
+func escapeMarkdownLine(s string) string {
+	trimmed := strings.TrimLeft(s, " \t")
+	leading := s[:len(s)-len(trimmed)]
+	body := strings.TrimRight(trimmed, " \t")
+	trailing := trimmed[len(body):]
+
+	body = escapeInline(body)
+	if m := orderedStart.FindStringSubmatchIndex(body); m != nil {
+		// "1. text" would be an ordered list item
+		body = body[:m[4]] + "\\" + body[m[4]:]
+	} else if body != "" && strings.ContainsRune("#>-+=|", rune(body[0])) {
+		body = "\\" + body
+	}
+
+	return encodeSpace(leading) + body + encodeSpace(trailing)
+}
+
+// escapeHeading escapes heading text, including any trailing "#"s that
+// would be taken as a closing sequence.
+func escapeHeading(s string) string {
+	s = escapeInline(s)
+	if strings.HasSuffix(s, "#") {
+		s = s[:len(s)-1] + "\\#"
+	}
+
+	return s
+}
+
+// encodeSpace replaces whitespace with character references, so it
+// isn't stripped.
+func encodeSpace(s string) string {
+	return strings.NewReplacer(" ", "&#32;", "\t", "&#9;").Replace(s)
+}
+
+// codeFenceFor returns a code fence for the supplied preformatted
+// block, long enough not to be closed by any of its lines.
+func codeFenceFor(p Preformatted) string {
+	char := "`"
+	// Backticks aren't allowed in the info string of backtick fences
+	if strings.Contains(p.Alt, "`") {
+		char = "~"
+	}
+
+	n := 3
+	for _, line := range p.Lines {
+		trimmed := strings.TrimLeft(line, " ")
+		run := len(trimmed) - len(strings.TrimLeft(trimmed, char))
+		if run >= n {
+			n = run + 1
+		}
+	}
+
+	return strings.Repeat(char, n)
+}
+
+// mdParser converts Markdown to a gemtext document, one block at a
+// time.
+type mdParser struct {
+	lines []string
+	pos   int
+	refs  map[string]Link
+	doc   *Document
+}
+
+// ParseMarkdown converts the Markdown read from the supplied reader to
+// a gemtext document.
+//
+// Inline links and images are flattened to their text, with a link line
+// for each following the paragraph, list or quote they appear in; a
+// line holding nothing but a link becomes a link line in its place.
+// Hard-wrapped paragraphs are unwrapped, though hard line breaks are
+// kept. Headings below level three are reduced to it, tables become
+// preformatted blocks, ordered list items become text lines keeping
+// their numbers, and inline formatting is removed. Text that would be
+// read as another type of line, such as a paragraph starting with "=>",
+// or a code line starting with "```", is prefixed with a space.
+func ParseMarkdown(r io.Reader) (*Document, error) {
+	var lines []string
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		lines = append(lines, strings.TrimSuffix(s.Text(), "\r"))
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("error reading Markdown: %w", err)
+	}
+
+	p := &mdParser{
+		lines: lines,
+		refs:  make(map[string]Link),
+		doc:   &Document{},
+	}
+	p.collectRefs()
+	for p.pos < len(p.lines) {
+		p.block()
+	}
+
+	return p.doc, nil
+}
+
+// collectRefs finds the link reference definitions in the document,
+// removing them so they aren't output.
+func (p *mdParser) collectRefs() {
+	var lines []string
+	fenced := ""
+	for _, line := range p.lines {
+		if m := codeFence.FindStringSubmatch(line); m != nil {
+			if fenced == "" {
+				fenced = m[1]
+			} else if strings.HasPrefix(m[1], fenced) && m[2] == "" {
+				fenced = ""
+			}
+		}
+		if fenced == "" {
+			if m := refDefinition.FindStringSubmatch(line); m != nil {
+				ref := normaliseRef(m[1])
+				if _, ok := p.refs[ref]; !ok {
+					p.refs[ref] = Link{URL: m[2]}
+				}
+				continue
+			}
+		}
+		lines = append(lines, line)
+	}
+	p.lines = lines
+}
+
+// add appends lines to the document, escaped so they're read back as
+// the same lines.
+func (p *mdParser) add(lines ...Line) {
+	for _, line := range lines {
+		p.doc.Lines = append(p.doc.Lines, escapeLine(line))
+	}
+}
+
+// block parses the block starting at the current line.
+func (p *mdParser) block() {
+	line := p.lines[p.pos]
+
+	switch {
+	case strings.TrimSpace(line) == "":
+		p.add(Text{})
+		p.pos++
+	case codeFence.MatchString(line):
+		p.fencedCode()
+	case atxHeading.MatchString(line):
+		m := atxHeading.FindStringSubmatch(line)
+		p.heading(len(m[1]), m[2])
+		p.pos++
+	case thematicBreak.MatchString(line):
+		p.add(Text{Text: "---"})
+		p.pos++
+	case quoteMarker.MatchString(line):
+		p.quote()
+	case p.isTable():
+		p.table()
+	case listMarker.MatchString(line) && !strings.HasPrefix(line, "    "):
+		p.list()
+	case strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t"):
+		p.indentedCode()
+	default:
+		p.paragraph()
+	}
+}
+
+// heading adds a heading of the supplied Markdown level and text,
+// followed by any links within it.
+func (p *mdParser) heading(level int, text string) {
+	if level > 3 {
+		level = 3
+	}
+	text, links := flattenInline(strings.TrimSpace(text), p.refs)
+	p.add(Heading{Level: level, Text: text})
+	p.addLinks(links)
+}
+
+// fencedCode parses a fenced code block into a preformatted block,
+// using its info string as alt text. A block not closed by the end of
+// the document is left unterminated.
+func (p *mdParser) fencedCode() {
+	m := codeFence.FindStringSubmatch(p.lines[p.pos])
+	fence := m[1]
+	indent := len(p.lines[p.pos]) - len(strings.TrimLeft(p.lines[p.pos], " "))
+	pre := Preformatted{Alt: strings.TrimSpace(unescapePunct(m[2])), unterminated: true}
+
+	for p.pos++; p.pos < len(p.lines); p.pos++ {
+		line := p.lines[p.pos]
+		if c := codeFence.FindStringSubmatch(line); c != nil && strings.HasPrefix(c[1], fence) && strings.TrimSpace(c[2]) == "" {
+			pre.unterminated = false
+			p.pos++
+			break
+		}
+		// Remove the fence's indentation from each line
+		for i := 0; i < indent && strings.HasPrefix(line, " "); i++ {
+			line = line[1:]
+		}
+		pre.Lines = append(pre.Lines, line)
+	}
+	p.add(pre)
+}
+
+// indentedCode parses an indented code block into a preformatted
+// block.
+func (p *mdParser) indentedCode() {
+	var pre Preformatted
+	for ; p.pos < len(p.lines); p.pos++ {
+		line := p.lines[p.pos]
+		if strings.HasPrefix(line, "\t") {
+			line = line[1:]
+		} else if strings.HasPrefix(line, "    ") {
+			line = line[4:]
+		} else if strings.TrimSpace(line) != "" {
+			break
+		}
+		pre.Lines = append(pre.Lines, line)
+	}
+
+	// Trailing blank lines separate the block from the next
+	for len(pre.Lines) > 0 && strings.TrimSpace(pre.Lines[len(pre.Lines)-1]) == "" {
+		pre.Lines = pre.Lines[:len(pre.Lines)-1]
+		p.pos--
+	}
+	p.add(pre)
+}
+
+// paragraph parses a paragraph, which may turn out to be a setext
+// heading.
+func (p *mdParser) paragraph() {
+	var lines []string
+	for ; p.pos < len(p.lines); p.pos++ {
+		line := p.lines[p.pos]
+		if len(lines) > 0 {
+			if m := setextLine.FindStringSubmatch(line); m != nil {
+				level := 1
+				if m[1][0] == '-' {
+					level = 2
+				}
+				p.heading(level, strings.Join(unwrap(lines), " "))
+				p.pos++
+				return
+			}
+			if p.interrupts(line) {
+				break
+			}
+		}
+		lines = append(lines, line)
+	}
+
+	var links []Link
+	for _, line := range unwrap(lines) {
+		if l, ok := p.linkLine(line); ok {
+			p.add(l)
+			continue
+		}
+		text, inline := flattenInline(line, p.refs)
+		p.add(Text{Text: text})
+		links = append(links, inline...)
+	}
+	p.addLinks(links)
+}
+
+// interrupts reports whether the supplied line ends a paragraph.
+func (p *mdParser) interrupts(line string) bool {
+	if strings.TrimSpace(line) == "" ||
+		codeFence.MatchString(line) ||
+		atxHeading.MatchString(line) ||
+		thematicBreak.MatchString(line) ||
+		quoteMarker.MatchString(line) ||
+		p.isTable() {
+		return true
+	}
+
+	// Only bullets and lists starting at 1 interrupt paragraphs
+	if m := listMarker.FindStringSubmatch(line); m != nil && m[2] != "" {
+		return !unicode.IsDigit(rune(m[1][0])) || strings.HasPrefix(m[1], "1") && len(m[1]) == 2
+	}
+
+	return false
+}
+
+// linkLine returns the link line for the supplied line of a paragraph,
+// if it holds nothing but a single link or image.
+func (p *mdParser) linkLine(line string) (Link, bool) {
+	line = strings.TrimSpace(line)
+	if m := autolink.FindStringSubmatch(line); m != nil && len(m[0]) == len(line) {
+		return Link{URL: m[1]}, true
+	}
+
+	// Images become links too
+	line = strings.TrimPrefix(line, "!")
+	if !strings.HasPrefix(line, "[") {
+		return Link{}, false
+	}
+	l, n := parseInlineLink(line, p.refs)
+	if n == 0 || n != len(line) {
+		return Link{}, false
+	}
+
+	l.Label, _ = flattenInline(l.Label, p.refs)
+	if l.Label == l.URL {
+		l.Label = ""
+	}
+
+	return l, true
+}
+
+// quote parses a block quote, adding a quote line for each of its
+// lines, and following them with any links within it.
+func (p *mdParser) quote() {
+	var links []Link
+	var para []string
+
+	flush := func() {
+		for _, line := range unwrap(para) {
+			text, inline := flattenInline(line, p.refs)
+			p.add(Quote{Text: " " + text})
+			links = append(links, inline...)
+		}
+		para = nil
+	}
+
+	for ; p.pos < len(p.lines); p.pos++ {
+		line := p.lines[p.pos]
+		if !quoteMarker.MatchString(line) {
+			break
+		}
+		// Nested quotes are flattened
+		for quoteMarker.MatchString(line) {
+			line = quoteMarker.ReplaceAllString(line, "")
+		}
+		if strings.TrimSpace(line) == "" {
+			flush()
+			p.add(Quote{})
+			continue
+		}
+		para = append(para, line)
+	}
+	flush()
+	p.addLinks(links)
+}
+
+// list parses a list, adding a list item line for each of its items,
+// and following them with any links within it. Items of ordered lists
+// become text lines, keeping their numbers.
+func (p *mdParser) list() {
+	var links []Link
+	var marker string
+	var item []string
+
+	flush := func() {
+		if item == nil {
+			return
+		}
+		text, inline := flattenInline(strings.Join(unwrap(item), " "), p.refs)
+		if unicode.IsDigit(rune(marker[0])) {
+			p.add(Text{Text: marker + " " + text})
+		} else {
+			p.add(ListItem{Text: text})
+		}
+		links = append(links, inline...)
+		item = nil
+	}
+
+	for ; p.pos < len(p.lines); p.pos++ {
+		line := p.lines[p.pos]
+		if m := listMarker.FindStringSubmatch(line); m != nil && !thematicBreak.MatchString(line) {
+			flush()
+			marker = m[1]
+			item = []string{m[2]}
+			continue
+		}
+		// Items continue over indented lines; anything else ends
+		// the list
+		if strings.TrimSpace(line) == "" || !strings.HasPrefix(line, "  ") && !strings.HasPrefix(line, "\t") {
+			break
+		}
+		item = append(item, strings.TrimSpace(line))
+	}
+	flush()
+	p.addLinks(links)
+}
+
+// isTable reports whether a table starts at the current line: a row
+// of cells followed by a delimiter row.
+func (p *mdParser) isTable() bool {
+	if p.pos+1 >= len(p.lines) {
+		return false
+	}
+	line := p.lines[p.pos]
+
+	return strings.Contains(line, "|") &&
+		tableDelim.MatchString(p.lines[p.pos+1]) &&
+		strings.Contains(p.lines[p.pos+1], "-") &&
+		len(tableCells(line)) == len(tableCells(p.lines[p.pos+1]))
+}
+
+// table parses a table into a preformatted block, with its columns
+// aligned, followed by any links within it.
+func (p *mdParser) table() {
+	var rows [][]string
+	var links []Link
+
+	header := p.lines[p.pos]
+	aligns := tableCells(p.lines[p.pos+1])
+	for i, cell := range aligns {
+		switch {
+		case strings.HasPrefix(cell, ":") && strings.HasSuffix(cell, ":"):
+			aligns[i] = "center"
+		case strings.HasSuffix(cell, ":"):
+			aligns[i] = "right"
+		default:
+			aligns[i] = "left"
+		}
+	}
+
+	addRow := func(line string) {
+		cells := tableCells(line)
+		row := make([]string, len(aligns))
+		for i := range row {
+			if i < len(cells) {
+				var inline []Link
+				row[i], inline = flattenInline(cells[i], p.refs)
+				links = append(links, inline...)
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	addRow(header)
+	for p.pos += 2; p.pos < len(p.lines); p.pos++ {
+		line := p.lines[p.pos]
+		if strings.TrimSpace(line) == "" || !strings.Contains(line, "|") {
+			break
+		}
+		addRow(line)
+	}
+
+	p.add(Preformatted{Alt: "table", Lines: formatTable(rows, aligns)})
+	p.addLinks(links)
+}
+
+// tableCells splits a table row into its trimmed cells.
+func tableCells(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, "\\|") {
+		line = line[:len(line)-1]
+	}
+
+	var cells []string
+	start := 0
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '|':
+			cells = append(cells, strings.TrimSpace(line[start:i]))
+			start = i + 1
+		}
+	}
+
+	return append(cells, strings.TrimSpace(line[start:]))
+}
+
+// formatTable lays out the supplied rows of cells in aligned columns,
+// with a rule under the header row.
+func formatTable(rows [][]string, aligns []string) []string {
+	widths := make([]int, len(aligns))
+	for _, row := range rows {
+		for i, cell := range row {
+			if w := displayWidth(cell); w > widths[i] {
+				widths[i] = w
+			}
+		}
+	}
+
+	var lines []string
+	for r, row := range rows {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			pad := widths[i] - displayWidth(cell)
+			switch aligns[i] {
+			case "right":
+				cells[i] = strings.Repeat(" ", pad) + cell
+			case "center":
+				cells[i] = strings.Repeat(" ", pad/2) + cell + strings.Repeat(" ", pad-pad/2)
+			default:
+				cells[i] = cell + strings.Repeat(" ", pad)
+			}
+		}
+		lines = append(lines, strings.TrimRight(strings.Join(cells, " | "), " "))
+
+		if r == 0 {
+			rules := make([]string, len(widths))
+			for i, w := range widths {
+				rules[i] = strings.Repeat("-", w)
+			}
+			lines = append(lines, strings.Join(rules, "-+-"))
+		}
+	}
+
+	return lines
+}
+
+// addLinks adds link lines for the supplied links.
+func (p *mdParser) addLinks(links []Link) {
+	for _, l := range links {
+		if l.Label == l.URL {
+			l.Label = ""
+		}
+		p.add(l)
+	}
+}
+
+// unwrap joins the lines of a hard-wrapped paragraph, keeping hard
+// line breaks: a backslash or two or more spaces ending a line.
+func unwrap(lines []string) []string {
+	var unwrapped []string
+	var current []string
+
+	for _, line := range lines {
+		line = strings.TrimLeft(line, " \t")
+		switch {
+		case trailingBackslashes(line)%2 == 1:
+			current = append(current, line[:len(line)-1])
+		case strings.HasSuffix(line, "  "):
+			current = append(current, strings.TrimRight(line, " "))
+		default:
+			current = append(current, strings.TrimRight(line, " \t"))
+			continue
+		}
+		unwrapped = append(unwrapped, strings.Join(current, " "))
+		current = nil
+	}
+	if current != nil {
+		unwrapped = append(unwrapped, strings.Join(current, " "))
+	}
+
+	return unwrapped
+}
+
+// trailingBackslashes returns the number of backslashes ending s. An
+// odd number ends it with a hard line break, rather than an escaped
+// backslash.
+func trailingBackslashes(s string) int {
+	return len(s) - len(strings.TrimRight(s, "\\"))
+}
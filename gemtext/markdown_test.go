@@ -0,0 +1,243 @@
+package gemtext
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseMarkdown(t *testing.T) {
+	testCases := map[string]struct {
+		input    string
+		expected string
+	}{
+		"unwrapped paragraphs": {
+			input:    "A hard-wrapped\nparagraph of text.\n\nAnother one.\n",
+			expected: "A hard-wrapped paragraph of text.\n\nAnother one.\n",
+		},
+		"hard line breaks": {
+			input:    "One\\\nTwo  \nThree\n",
+			expected: "One\nTwo\nThree\n",
+		},
+		"inline links": {
+			input:    "See [the docs](gemini://some.url/docs) and\n[the FAQ][faq].\n\n[faq]: /faq.gmi \"FAQ\"\n",
+			expected: "See the docs and the FAQ.\n=> gemini://some.url/docs the docs\n=> /faq.gmi the FAQ\n\n",
+		},
+		"link lines": {
+			input:    "[Home](/)\\\n<gemini://some.url/>\n",
+			expected: "=> / Home\n=> gemini://some.url/\n",
+		},
+		"images": {
+			input:    "![A rocket](rocket.png)\n",
+			expected: "=> rocket.png A rocket\n",
+		},
+		"inline formatting": {
+			input:    "Some **bold**, _emphasised_, ~~struck~~ and `<code>` text; 2 * 3 = snake_case_name.\n",
+			expected: "Some bold, emphasised, struck and <code> text; 2 * 3 = snake_case_name.\n",
+		},
+		"escapes and entities": {
+			input:    "\\*Not emphasised\\* &amp; &lt;b&gt;\n",
+			expected: "*Not emphasised* & <b>\n",
+		},
+		"headings": {
+			input:    "# One #\n## Two\n#### Four\n###### Six\nSetext\n======\n",
+			expected: "# One\n## Two\n### Four\n### Six\n# Setext\n",
+		},
+		"lists": {
+			input:    "- One\n- A [link](/two)\n  continued\n* Three\n\n1. First\n2. Second\n",
+			expected: "* One\n* A link continued\n* Three\n=> /two link\n\n1. First\n2. Second\n",
+		},
+		"quotes": {
+			input:    "> A quote\n> over two lines\n>\n> with [a link](/q)\n",
+			expected: "> A quote over two lines\n>\n> with a link\n=> /q a link\n",
+		},
+		"fenced code": {
+			input:    "```go\nfunc main() {\n\n}\n```\n~~~\n[not](/a/link)\n~~~\n",
+			expected: "```go\nfunc main() {\n\n}\n```\n```\n[not](/a/link)\n```\n",
+		},
+		"indented code": {
+			input:    "Text\n\n    code\n      indented\n\nMore text\n",
+			expected: "Text\n\n```\ncode\n  indented\n```\n\nMore text\n",
+		},
+		"tables": {
+			input: "| Name | Size |\n|:-----|-----:|\n| [a](/a) | 1 |\n| bb | 200 |\n",
+			expected: "```table\nName | Size\n-----+-----\na    |    1\nbb   |  200\n```\n" +
+				"=> /a a\n",
+		},
+		"thematic break": {
+			input:    "One\n\n***\n\nTwo\n",
+			expected: "One\n\n---\n\nTwo\n",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			doc, err := ParseMarkdown(strings.NewReader(tc.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+			if got := doc.String(); got != tc.expected {
+				t.Errorf("got '%s', want '%s'", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestParseMarkdownReparse(t *testing.T) {
+	testCases := map[string]struct {
+		input    string
+		expected []Line
+	}{
+		"escaped heading": {
+			input:    "\\# Not a heading\n",
+			expected: []Line{Text{Text: " # Not a heading"}},
+		},
+		"link marker": {
+			input:    "=> not a link\n",
+			expected: []Line{Text{Text: " => not a link"}},
+		},
+		"quote marker": {
+			input:    "\\> not a quote\n",
+			expected: []Line{Text{Text: " > not a quote"}},
+		},
+		"list marker": {
+			input:    "\\* not a list item\n",
+			expected: []Line{Text{Text: " * not a list item"}},
+		},
+		"toggle in paragraph": {
+			input:    "\\`\\`\\` not a toggle\n",
+			expected: []Line{Text{Text: " ``` not a toggle"}},
+		},
+		"ordered list item": {
+			input:    "1. => not a link\n",
+			expected: []Line{Text{Text: "1. => not a link"}},
+		},
+		"toggle in fenced code": {
+			input:    "~~~md\n```go\ncode\n```\n~~~\nText\n",
+			expected: []Line{Preformatted{Alt: "md", Lines: []string{" ```go", "code", " ```"}}, Text{Text: "Text"}},
+		},
+		"toggle in indented code": {
+			input:    "    ```\n    code\n",
+			expected: []Line{Preformatted{Lines: []string{" ```", "code"}}},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			doc, err := ParseMarkdown(strings.NewReader(tc.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+			got, err := Parse(strings.NewReader(doc.String()))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(got.Lines) != len(tc.expected) {
+				t.Fatalf("got %d lines, want %d, from '%s'", len(got.Lines), len(tc.expected), doc.String())
+			}
+			for i, line := range got.Lines {
+				if got := withoutSource(line); !reflect.DeepEqual(got, tc.expected[i]) {
+					t.Errorf("line %d: got %#v, want %#v", i, got, tc.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	input := "# Title #\n\nSome *text* with 1 < 2 & snake_case.\n=> gemini://some.url/ A link\n" +
+		"=> /relative\n\n* Item\n> Quote\n> more\n```sh\nls -l\n```\n"
+	expected := "# Title \\#\n\nSome \\*text\\* with 1 \\< 2 & snake_case.\\\n[A link](gemini://some.url/)\\\n" +
+		"[/relative](/relative)\n\n* Item\n> Quote\\\n> more\n```sh\nls -l\n```\n"
+
+	doc, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got bytes.Buffer
+	if err := WriteMarkdown(&got, doc); err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+	if got.String() != expected {
+		t.Errorf("got '%s', want '%s'", got.String(), expected)
+	}
+}
+
+func TestMarkdownRoundTrip(t *testing.T) {
+	testCases := map[string]string{
+		"block markers":        "#not a heading\n- not a list\n+ nor this\n1. nor this\n= setext?\n---\n| a | b |\n",
+		"inline markup":        "*stars* _under_ `code` [brackets] <tag> &amp; ~~tilde~~ ~user\\path\n",
+		"whitespace":           "  indented\ntrailing  \n\t\n",
+		"links":                "=> gemini://some.url/ Label with [brackets]\n=> /a(b) Parens\n=> /no-label\nText after links\n",
+		"quotes and lists":     "> One\n> Two\n>\n* Item *one*\n* Item\nText\n",
+		"headings":             "# C#\n## #hashtag\n### Heading\n",
+		"preformatted":         "```alt `text`\n```\n````\n```\n",
+		"closing toggle text":  "```\ncode\n``` ignored text\nAfter\n",
+		"unterminated":         "Text\n```sh\ncode\n\n",
+		"unterminated empty":   "```\n",
+		"unterminated toggles": "```md\n ```go\n",
+	}
+
+	// The test documents used elsewhere should round-trip too
+	paths, err := filepath.Glob(filepath.Join("testdata", "*.input"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testCases[path] = string(b)
+	}
+
+	for name, input := range testCases {
+		t.Run(name, func(t *testing.T) {
+			doc, err := Parse(strings.NewReader(input))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var md bytes.Buffer
+			if err := WriteMarkdown(&md, doc); err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+			got, err := ParseMarkdown(&md)
+			if err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+
+			if got.String() != canonical(doc) {
+				t.Errorf("got '%q', want '%q' via Markdown '%q'", got.String(), canonical(doc), md.String())
+			}
+		})
+	}
+}
+
+// canonical serialises the supplied document as it would be if
+// constructed rather than parsed, with whitespace after the prefixes of
+// quotes and list items normalised.
+func canonical(doc *Document) string {
+	var c Document
+	for _, line := range doc.Lines {
+		switch l := withoutSource(line).(type) {
+		case Quote:
+			if l.Text = strings.TrimLeft(l.Text, " \t"); l.Text != "" {
+				l.Text = " " + l.Text
+			}
+			c.Lines = append(c.Lines, l)
+		case ListItem:
+			l.Text = strings.TrimLeft(l.Text, " \t")
+			c.Lines = append(c.Lines, l)
+		default:
+			c.Lines = append(c.Lines, withoutSource(line))
+		}
+	}
+
+	return c.String()
+}
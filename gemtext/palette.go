@@ -0,0 +1,123 @@
+package gemtext
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/chriswalker/gmi-utils/terminal"
+)
+
+// ansi16 holds the RGB values of the 16 ANSI colours, as xterm shows
+// them by default.
+var ansi16 = [16]Colour{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+	{0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+	{92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+// cubeLevels are the intensities of each channel in the 6x6x6 colour
+// cube of the xterm 256 colour palette.
+var cubeLevels = [6]uint8{0, 95, 135, 175, 215, 255}
+
+// ColourDepth sets the range of colours output is coloured with. Theme
+// colours are approximated by the nearest colour available, and output
+// is left unstyled at terminal.NoColour. Output is in 24-bit colour
+// unless set otherwise.
+func ColourDepth(depth terminal.ColourDepth) Option {
+	return func(o *outputOptions) {
+		o.depth = depth
+	}
+}
+
+// sgr returns the SGR parameters setting the foreground, or if
+// background is set the background, to the colour, or the nearest to
+// it at the supplied colour depth.
+func (c Colour) sgr(depth terminal.ColourDepth, background bool) string {
+	base := 30
+	if background {
+		base = 40
+	}
+
+	switch depth {
+	case terminal.TrueColour:
+		return fmt.Sprintf("%d;2;%d;%d;%d", base+8, c.red, c.green, c.blue)
+	case terminal.Colour256:
+		return fmt.Sprintf("%d;5;%d", base+8, c.ansi256())
+	case terminal.Colour16:
+		// The bright colours have their own codes
+		i := c.ansi16()
+		if i >= 8 {
+			return strconv.Itoa(base + 60 + i - 8)
+		}
+		return strconv.Itoa(base + i)
+	default:
+		return ""
+	}
+}
+
+// ansi256 returns the index of the nearest colour in the xterm 256
+// colour palette, from either its colour cube or its greyscale ramp.
+// The first 16 colours are skipped, as terminals often redefine them.
+func (c Colour) ansi256() int {
+	r, g, b := nearestLevel(c.red), nearestLevel(c.green), nearestLevel(c.blue)
+	cube := Colour{cubeLevels[r], cubeLevels[g], cubeLevels[b]}
+
+	// The greyscale ramp runs from 8 to 238 in steps of 10
+	avg := (int(c.red) + int(c.green) + int(c.blue)) / 3
+	grey := (avg - 3) / 10
+	if grey < 0 {
+		grey = 0
+	} else if grey > 23 {
+		grey = 23
+	}
+	level := uint8(8 + grey*10)
+
+	if c.distance(Colour{level, level, level}) < c.distance(cube) {
+		return 232 + grey
+	}
+	return 16 + r*36 + g*6 + b
+}
+
+// ansi16 returns the index of the nearest of the 16 ANSI colours.
+func (c Colour) ansi16() int {
+	nearest := 0
+	for i, colour := range ansi16 {
+		if c.distance(colour) < c.distance(ansi16[nearest]) {
+			nearest = i
+		}
+	}
+
+	return nearest
+}
+
+// distance returns the squared distance between two colours in RGB
+// space.
+func (c Colour) distance(other Colour) int {
+	dr := int(c.red) - int(other.red)
+	dg := int(c.green) - int(other.green)
+	db := int(c.blue) - int(other.blue)
+
+	return dr*dr + dg*dg + db*db
+}
+
+// nearestLevel returns the index of the colour cube level nearest to
+// the supplied channel intensity.
+func nearestLevel(v uint8) int {
+	nearest := 0
+	for i, level := range cubeLevels {
+		if absDiff(v, level) < absDiff(v, cubeLevels[nearest]) {
+			nearest = i
+		}
+	}
+
+	return nearest
+}
+
+// absDiff returns the absolute difference between a and b.
+func absDiff(a, b uint8) int {
+	if a > b {
+		return int(a - b)
+	}
+	return int(b - a)
+}
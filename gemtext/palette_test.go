@@ -0,0 +1,58 @@
+package gemtext
+
+import (
+	"testing"
+
+	"github.com/chriswalker/gmi-utils/terminal"
+)
+
+func TestColourEscape(t *testing.T) {
+	testCases := map[string]struct {
+		colour   string
+		depth    terminal.ColourDepth
+		expected string
+	}{
+		"truecolor": {
+			colour:   "#81a1c1",
+			depth:    terminal.TrueColour,
+			expected: "\u001B[38;2;129;161;193m",
+		},
+		"256 colour cube": {
+			colour:   "#81a1c1",
+			depth:    terminal.Colour256,
+			expected: "\u001B[38;5;109m",
+		},
+		"256 greyscale": {
+			colour:   "#4e4e4e",
+			depth:    terminal.Colour256,
+			expected: "\u001B[38;5;239m",
+		},
+		"256 pure colour": {
+			colour:   "#ff0000",
+			depth:    terminal.Colour256,
+			expected: "\u001B[38;5;196m",
+		},
+		"16 colours": {
+			colour:   "#c00000",
+			depth:    terminal.Colour16,
+			expected: "\u001B[31m",
+		},
+		"16 bright colours": {
+			colour:   "#ffff40",
+			depth:    terminal.Colour16,
+			expected: "\u001B[93m",
+		},
+		"no colour": {
+			colour: "#ffffff",
+			depth:  terminal.NoColour,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if got := (Style{Foreground: NewColour(tc.colour)}).escape(tc.depth); got != tc.expected {
+				t.Errorf("got %q, want %q", got, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package gemtext
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Renderer renders gemtext documents line by line, as passed to it by
+// Render. Implementations write wherever suits them: the renderers in
+// this package write to an io.Writer given to their constructors.
+type Renderer interface {
+	// Start is called before the first line of a document.
+	Start() error
+	Text(line Text) error
+	Link(line Link) error
+	Heading(line Heading) error
+	ListItem(line ListItem) error
+	Quote(line Quote) error
+	// Preformatted is called once per preformatted block, with all
+	// its lines.
+	Preformatted(line Preformatted) error
+	// End is called after the last line of a document.
+	End() error
+}
+
+// Render parses the gemtext in the supplied reader, passing each line
+// in turn to the method of the renderer for its type.
+func Render(r io.Reader, renderer Renderer) error {
+	if err := renderer.Start(); err != nil {
+		return err
+	}
+
+	p := NewParser(r)
+	for {
+		line, err := p.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("error reading gemtext: %w", err)
+		}
+
+		switch l := line.(type) {
+		case Text:
+			err = renderer.Text(l)
+		case Link:
+			err = renderer.Link(l)
+		case Heading:
+			err = renderer.Heading(l)
+		case ListItem:
+			err = renderer.ListItem(l)
+		case Quote:
+			err = renderer.Quote(l)
+		case Preformatted:
+			err = renderer.Preformatted(l)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return renderer.End()
+}
+
+// NewTextRenderer returns a Renderer formatting gemtext as plain text
+// wrapped to the supplied width, free of escape codes, as is suitable
+// for email. Unless set otherwise with LinkReferences, links are
+// numbered and their URLs listed at the end.
+func NewTextRenderer(w io.Writer, width int, opts ...Option) Renderer {
+	opts = append([]Option{LinkReferences(ReferencesEnd)}, opts...)
+	t := newTerminalRenderer(w, width, 0, opts...)
+	t.o.theme = Theme{}
+
+	return t
+}
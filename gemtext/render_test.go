@@ -0,0 +1,120 @@
+package gemtext
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// recorder is a Renderer recording the calls made to it.
+type recorder struct {
+	calls []string
+}
+
+func (r *recorder) Start() error {
+	r.calls = append(r.calls, "start")
+	return nil
+}
+
+func (r *recorder) Text(line Text) error {
+	r.calls = append(r.calls, "text "+line.Text)
+	return nil
+}
+
+func (r *recorder) Link(line Link) error {
+	r.calls = append(r.calls, "link "+line.URL+" "+line.Label)
+	return nil
+}
+
+func (r *recorder) Heading(line Heading) error {
+	r.calls = append(r.calls, fmt.Sprintf("heading %d %s", line.Level, line.Text))
+	return nil
+}
+
+func (r *recorder) ListItem(line ListItem) error {
+	r.calls = append(r.calls, "list item "+line.Text)
+	return nil
+}
+
+func (r *recorder) Quote(line Quote) error {
+	r.calls = append(r.calls, "quote "+line.Text)
+	return nil
+}
+
+func (r *recorder) Preformatted(line Preformatted) error {
+	r.calls = append(r.calls, "preformatted "+line.Alt+" "+strings.Join(line.Lines, "|"))
+	return nil
+}
+
+func (r *recorder) End() error {
+	r.calls = append(r.calls, "end")
+	return nil
+}
+
+func TestRender(t *testing.T) {
+	input := "## Title\nText\n=> /url Label\n* Item\n> Quote\n```alt\none\ntwo\n```\n"
+	expected := []string{
+		"start",
+		"heading 2 Title",
+		"text Text",
+		"link /url Label",
+		"list item Item",
+		"quote  Quote",
+		"preformatted alt one|two",
+		"end",
+	}
+
+	var r recorder
+	if err := Render(strings.NewReader(input), &r); err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+	if !reflect.DeepEqual(r.calls, expected) {
+		t.Errorf("got %q, want %q", r.calls, expected)
+	}
+}
+
+func TestTextRenderer(t *testing.T) {
+	testCases := map[string]struct {
+		input    string
+		width    int
+		opts     []Option
+		expected string
+	}{
+		"wrapped": {
+			input:    "Some text to wrap\n",
+			width:    10,
+			expected: "Some text\nto wrap\n",
+		},
+		"references at end": {
+			input:    "# Title\n=> gemini://some.url/ Some URL\nText\n=> /other\n",
+			width:    72,
+			expected: "# Title\n[1] Some URL\nText\n[2] /other\n\n[1] gemini://some.url/\n[2] /other\n",
+		},
+		"references overridden": {
+			input:    "=> gemini://some.url/ Some URL\n",
+			width:    72,
+			opts:     []Option{LinkReferences(ReferencesNone)},
+			expected: "Some URL [gemini://some.url/]\n",
+		},
+		"preformatted": {
+			input:    "```alt\n  code\n```\n",
+			width:    72,
+			opts:     []Option{AltText(true)},
+			expected: "[alt]\n  code\n",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var got bytes.Buffer
+			if err := Render(strings.NewReader(tc.input), NewTextRenderer(&got, tc.width, tc.opts...)); err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+			if got.String() != tc.expected {
+				t.Errorf("got '%s', want '%s'", got.String(), tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package gemtext
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/chriswalker/gmi-utils/terminal"
+)
+
+// Reset is the escape sequence resetting all text attributes and
+// colours.
+const Reset = "\u001B[0m"
+
+// Style describes how text is output: its foreground and background
+// colours, either of which may be nil to leave it as the terminal's
+// default, and text attributes. The zero Style leaves text unstyled.
+type Style struct {
+	Foreground    *Colour
+	Background    *Colour
+	Bold          bool
+	Dim           bool
+	Italic        bool
+	Underline     bool
+	Reverse       bool
+	Strikethrough bool
+}
+
+// styleAttributes maps the names of text attributes, as used in style
+// specs, to their SGR parameters.
+var styleAttributes = map[string]int{
+	"bold":          1,
+	"dim":           2,
+	"italic":        3,
+	"underline":     4,
+	"reverse":       7,
+	"strikethrough": 9,
+}
+
+// ParseStyle parses a style spec: a space-separated list of a
+// foreground colour, as "#rrggbb" or "#rgb", a background colour, as
+// "bg:#rrggbb", and text attributes, any of bold, dim, italic,
+// underline, reverse and strikethrough. For example:
+//
+//	#81a1c1 bold underline
+//	italic dim
+//	#2e3440 bg:#88c0d0
+func ParseStyle(spec string) (Style, error) {
+	var s Style
+	for _, field := range strings.Fields(spec) {
+		switch {
+		case strings.HasPrefix(field, "bg:"):
+			colour, err := parseColour(field[len("bg:"):])
+			if err != nil {
+				return Style{}, err
+			}
+			s.Background = colour
+		case strings.HasPrefix(field, "#"):
+			colour, err := parseColour(field)
+			if err != nil {
+				return Style{}, err
+			}
+			s.Foreground = colour
+		default:
+			if _, ok := styleAttributes[field]; !ok {
+				return Style{}, fmt.Errorf("unknown style attribute '%s'", field)
+			}
+			s.set(field)
+		}
+	}
+
+	return s, nil
+}
+
+// parseColour parses a "#rrggbb" or "#rgb" hex colour.
+func parseColour(hex string) (*Colour, error) {
+	if !strings.HasPrefix(hex, "#") || len(parseHexColor(hex)) != len(hex)-1 {
+		return nil, fmt.Errorf("invalid colour '%s'", hex)
+	}
+
+	return NewColour(hex), nil
+}
+
+// set turns on the named text attribute.
+func (s *Style) set(attribute string) {
+	switch attribute {
+	case "bold":
+		s.Bold = true
+	case "dim":
+		s.Dim = true
+	case "italic":
+		s.Italic = true
+	case "underline":
+		s.Underline = true
+	case "reverse":
+		s.Reverse = true
+	case "strikethrough":
+		s.Strikethrough = true
+	}
+}
+
+// over returns the style with the colours and attributes set in it
+// layered over those of base.
+func (s Style) over(base Style) Style {
+	if s.Foreground == nil {
+		s.Foreground = base.Foreground
+	}
+	if s.Background == nil {
+		s.Background = base.Background
+	}
+	s.Bold = s.Bold || base.Bold
+	s.Dim = s.Dim || base.Dim
+	s.Italic = s.Italic || base.Italic
+	s.Underline = s.Underline || base.Underline
+	s.Reverse = s.Reverse || base.Reverse
+	s.Strikethrough = s.Strikethrough || base.Strikethrough
+
+	return s
+}
+
+// escape returns the escape sequence applying the style at the supplied
+// colour depth, with colours approximated as necessary. It returns an
+// empty string for the zero Style, and for any style at
+// terminal.NoColour.
+func (s Style) escape(depth terminal.ColourDepth) string {
+	if depth == terminal.NoColour {
+		return ""
+	}
+
+	var params []string
+	for _, attr := range []struct {
+		set  bool
+		name string
+	}{
+		{s.Bold, "bold"},
+		{s.Dim, "dim"},
+		{s.Italic, "italic"},
+		{s.Underline, "underline"},
+		{s.Reverse, "reverse"},
+		{s.Strikethrough, "strikethrough"},
+	} {
+		if attr.set {
+			params = append(params, strconv.Itoa(styleAttributes[attr.name]))
+		}
+	}
+	if s.Foreground != nil {
+		params = append(params, s.Foreground.sgr(depth, false))
+	}
+	if s.Background != nil {
+		params = append(params, s.Background.sgr(depth, true))
+	}
+
+	if len(params) == 0 {
+		return ""
+	}
+	return "\u001B[" + strings.Join(params, ";") + "m"
+}
+
+// inlineStyle styles part of a line, such as a link's label, with the
+// supplied element style layered over the line's, before returning to
+// the line's style.
+func (o outputOptions) inlineStyle(s string, element, line Style) string {
+	if element == (Style{}) {
+		return s
+	}
+
+	styled := element.over(line).escape(o.depth)
+	if styled == "" {
+		return s
+	}
+	return styled + s + Reset + line.escape(o.depth)
+}
@@ -0,0 +1,166 @@
+package gemtext
+
+import (
+	"fmt"
+
+	"github.com/chriswalker/gmi-utils/config"
+)
+
+// Theme holds the styles that gemtext is output in, by line type, as
+// well as for elements of some lines: link labels and URLs, list
+// bullets and the bar before quotes, and the tokens of highlighted
+// preformatted code. Element styles are layered over those of their
+// lines. The zero Theme leaves output unstyled.
+//
+// Themes are passed to renderers by value and never modified by them,
+// so any number of documents may be rendered concurrently, each in
+// its own theme.
+type Theme struct {
+	Text         Style
+	Preformatted Style
+	Header       Style
+	Header2      Style
+	Header3      Style
+	Quoted       Style
+	QuoteBar     Style
+	Link         Style
+	LinkLabel    Style
+	LinkURL      Style
+	ListItem     Style
+	ListBullet   Style
+
+	SyntaxKeyword Style
+	SyntaxString  Style
+	SyntaxComment Style
+	SyntaxNumber  Style
+}
+
+// ThemeFromConfig returns a Theme with the styles set in the supplied
+// configuration, as parsed by ParseStyle. Styles are keyed by line
+// type, as text, preformatted, header, header2, header3, quoted, link
+// and listitem, by element, as quotebar, linklabel, linkurl and
+// bullet, or by the kind of token in highlighted code, as keyword,
+// string, comment and number.
+func ThemeFromConfig(conf config.Config) (Theme, error) {
+	var theme Theme
+	styles := map[string]*Style{
+		"text":         &theme.Text,
+		"preformatted": &theme.Preformatted,
+		"header":       &theme.Header,
+		"header2":      &theme.Header2,
+		"header3":      &theme.Header3,
+		"quoted":       &theme.Quoted,
+		"quotebar":     &theme.QuoteBar,
+		"link":         &theme.Link,
+		"linklabel":    &theme.LinkLabel,
+		"linkurl":      &theme.LinkURL,
+		"listitem":     &theme.ListItem,
+		"bullet":       &theme.ListBullet,
+		"keyword":      &theme.SyntaxKeyword,
+		"string":       &theme.SyntaxString,
+		"comment":      &theme.SyntaxComment,
+		"number":       &theme.SyntaxNumber,
+	}
+
+	for key, val := range conf {
+		style, ok := styles[key]
+		if !ok {
+			continue
+		}
+
+		s, err := ParseStyle(val)
+		if err != nil {
+			return Theme{}, fmt.Errorf("invalid style for '%s': %w", key, err)
+		}
+		*style = s
+	}
+
+	return theme, nil
+}
+
+// Colours sets the theme that terminal output is styled with.
+func Colours(theme Theme) Option {
+	return func(o *outputOptions) {
+		o.theme = theme
+	}
+}
+
+// configured holds the styles set with Configure, and defaultTheme the
+// theme built from them, which terminal output is styled with unless
+// given the Colours option.
+var (
+	configured   = make(config.Config)
+	defaultTheme Theme
+)
+
+// Configure takes any styles set in the given Config and applies them
+// to the line types they're keyed by, for terminal output not given a
+// theme with the Colours option. Styles set by earlier calls are kept
+// unless overridden, and invalid styles are ignored.
+//
+// Deprecated: Configure sets styles for the whole package, so can't be
+// used safely alongside concurrent output. Build a Theme with
+// ThemeFromConfig and pass it to Output with the Colours option
+// instead.
+func Configure(conf config.Config) {
+	for key, val := range conf {
+		if _, err := ParseStyle(val); err == nil {
+			configured[key] = val
+		}
+	}
+	defaultTheme, _ = ThemeFromConfig(configured)
+}
+
+// style returns the theme's style for the supplied line type.
+func (t Theme) style(lt lineType) Style {
+	switch lt {
+	case text:
+		return t.Text
+	case preformatted:
+		return t.Preformatted
+	case header:
+		return t.Header
+	case header2:
+		return t.Header2
+	case header3:
+		return t.Header3
+	case quoted:
+		return t.Quoted
+	case link:
+		return t.Link
+	case listItem:
+		return t.ListItem
+	default:
+		return Style{}
+	}
+}
+
+// markerStyle returns the theme's style for the marker of the supplied
+// line type, if it has one of its own.
+func (t Theme) markerStyle(lt lineType) (Style, bool) {
+	switch {
+	case lt == listItem && t.ListBullet != Style{}:
+		return t.ListBullet.over(t.ListItem), true
+	case lt == quoted && t.QuoteBar != Style{}:
+		return t.QuoteBar.over(t.Quoted), true
+	default:
+		return Style{}, false
+	}
+}
+
+// syntaxStyle returns the theme's style for the supplied kind of token
+// in highlighted code.
+func (t Theme) syntaxStyle(kind tokenKind) Style {
+	switch kind {
+	case keywordToken:
+		return t.SyntaxKeyword
+	case stringToken:
+		return t.SyntaxString
+	case commentToken:
+		return t.SyntaxComment
+	case numberToken:
+		return t.SyntaxNumber
+	default:
+		return Style{}
+	}
+}
@@ -0,0 +1,209 @@
+package gemtext
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/chriswalker/gmi-utils/config"
+	"github.com/chriswalker/gmi-utils/terminal"
+)
+
+func TestParseStyle(t *testing.T) {
+	testCases := map[string]struct {
+		spec     string
+		expected Style
+		err      bool
+	}{
+		"colour": {
+			spec:     "#81a1c1",
+			expected: Style{Foreground: &Colour{0x81, 0xa1, 0xc1}},
+		},
+		"colour and attributes": {
+			spec:     "#81a1c1 bold  underline",
+			expected: Style{Foreground: &Colour{0x81, 0xa1, 0xc1}, Bold: true, Underline: true},
+		},
+		"attributes only": {
+			spec:     "italic dim",
+			expected: Style{Italic: true, Dim: true},
+		},
+		"background": {
+			spec:     "#fff bg:#2e3440 reverse strikethrough",
+			expected: Style{Foreground: &Colour{255, 255, 255}, Background: &Colour{0x2e, 0x34, 0x40}, Reverse: true, Strikethrough: true},
+		},
+		"empty": {},
+		"invalid colour": {
+			spec: "#12345g",
+			err:  true,
+		},
+		"invalid background": {
+			spec: "bg:red",
+			err:  true,
+		},
+		"unknown attribute": {
+			spec: "#ffffff blinking",
+			err:  true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseStyle(tc.spec)
+			if tc.err {
+				if err == nil {
+					t.Errorf("expected error, got style %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("got %+v, want %+v", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestThemeFromConfig(t *testing.T) {
+	conf := config.Config{
+		"header": "#ff0000 bold",
+		"link":   "#00ff00",
+		"bullet": "dim",
+		"other":  "#0000ff",
+	}
+	expected := Theme{
+		Header:     Style{Foreground: &Colour{red: 255}, Bold: true},
+		Link:       Style{Foreground: &Colour{green: 255}},
+		ListBullet: Style{Dim: true},
+	}
+
+	got, err := ThemeFromConfig(conf)
+	if err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %+v, want %+v", got, expected)
+	}
+
+	if _, err := ThemeFromConfig(config.Config{"quoted": "shouty"}); err == nil {
+		t.Error("expected error for invalid style")
+	}
+}
+
+func TestOutputThemes(t *testing.T) {
+	input := "# Heading\n=> /url Label\n* Item\n> Quote\n"
+	red := NewColour("#ff0000")
+	blue := NewColour("#0000ff")
+
+	testCases := map[string]struct {
+		theme    Theme
+		opts     []Option
+		expected string
+	}{
+		"unstyled": {
+			expected: "# Heading\nLabel [/url]\n* Item\n> Quote\n",
+		},
+		"red headers": {
+			theme:    Theme{Header: Style{Foreground: red}},
+			expected: "\u001B[38;2;255;0;0m# Heading\u001B[0m\nLabel [/url]\n* Item\n> Quote\n",
+		},
+		"bold headers on blue": {
+			theme:    Theme{Header: Style{Bold: true, Background: blue}},
+			expected: "\u001B[1;48;2;0;0;255m# Heading\u001B[0m\nLabel [/url]\n* Item\n> Quote\n",
+		},
+		"link label and URL": {
+			theme: Theme{
+				Link:      Style{Foreground: blue},
+				LinkLabel: Style{Bold: true},
+				LinkURL:   Style{Dim: true},
+			},
+			expected: "# Heading\n" +
+				"\u001B[38;2;0;0;255m\u001B[1;38;2;0;0;255mLabel\u001B[0m\u001B[38;2;0;0;255m" +
+				" [\u001B[2;38;2;0;0;255m/url\u001B[0m\u001B[38;2;0;0;255m]\u001B[0m\n" +
+				"* Item\n> Quote\n",
+		},
+		"numbered link label": {
+			theme:    Theme{LinkLabel: Style{Underline: true}},
+			opts:     []Option{NumberLinks(true)},
+			expected: "# Heading\n[1] \u001B[4mLabel\u001B[0m\n* Item\n> Quote\n",
+		},
+		"bullet and quote bar": {
+			theme: Theme{
+				ListItem:   Style{Italic: true},
+				ListBullet: Style{Foreground: red},
+				QuoteBar:   Style{Dim: true},
+			},
+			expected: "# Heading\nLabel [/url]\n" +
+				"\u001B[3;38;2;255;0;0m*\u001B[0m\u001B[3m Item\u001B[0m\n" +
+				"\u001B[2m>\u001B[0m Quote\n",
+		},
+		"no colour": {
+			theme:    Theme{Header: Style{Foreground: red, Bold: true}},
+			opts:     []Option{ColourDepth(terminal.NoColour)},
+			expected: "# Heading\nLabel [/url]\n* Item\n> Quote\n",
+		},
+	}
+
+	// Themes are independent, so documents may be rendered concurrently
+	var wg sync.WaitGroup
+	got := make(map[string]string)
+	var mu sync.Mutex
+	for name, tc := range testCases {
+		wg.Add(1)
+		go func(name string, theme Theme, opts []Option) {
+			defer wg.Done()
+			var b bytes.Buffer
+			Output(80, 0, strings.NewReader(input), &b, append(opts, Colours(theme))...)
+			mu.Lock()
+			got[name] = b.String()
+			mu.Unlock()
+		}(name, tc.theme, tc.opts)
+	}
+	wg.Wait()
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if got[name] != tc.expected {
+				t.Errorf("got %q, want %q", got[name], tc.expected)
+			}
+		})
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	input := "# Heading\n> Quote\n"
+
+	// Configured styles are global; clear them once done
+	defer func() {
+		configured = make(config.Config)
+		defaultTheme = Theme{}
+	}()
+	Configure(config.Config{"header": "#ff0000", "quoted": "shouty"})
+	Configure(config.Config{"link": "#00ff00"})
+
+	testCases := map[string]struct {
+		opts     []Option
+		expected string
+	}{
+		"configured styles": {
+			expected: "\u001B[38;2;255;0;0m# Heading\u001B[0m\n> Quote\n",
+		},
+		"theme given": {
+			opts:     []Option{Colours(Theme{})},
+			expected: "# Heading\n> Quote\n",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var b bytes.Buffer
+			Output(80, 0, strings.NewReader(input), &b, tc.opts...)
+			if b.String() != tc.expected {
+				t.Errorf("got %q, want %q", b.String(), tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package gemtext
+
+import "unicode"
+
+// wide holds the characters occupying two terminal cells - those with
+// an East Asian Width of Wide or Fullwidth, plus emoji presented as
+// wide by default. It is a condensed version of the Unicode
+// EastAsianWidth.txt data, in the style of wcwidth(3).
+var wide = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{Lo: 0x1100, Hi: 0x115f, Stride: 1},
+		{Lo: 0x231a, Hi: 0x231b, Stride: 1},
+		{Lo: 0x2329, Hi: 0x232a, Stride: 1},
+		{Lo: 0x23e9, Hi: 0x23ec, Stride: 1},
+		{Lo: 0x23f0, Hi: 0x23f3, Stride: 3},
+		{Lo: 0x25fd, Hi: 0x25fe, Stride: 1},
+		{Lo: 0x2614, Hi: 0x2615, Stride: 1},
+		{Lo: 0x2648, Hi: 0x2653, Stride: 1},
+		{Lo: 0x267f, Hi: 0x2693, Stride: 20},
+		{Lo: 0x26a1, Hi: 0x26a1, Stride: 1},
+		{Lo: 0x26aa, Hi: 0x26ab, Stride: 1},
+		{Lo: 0x26bd, Hi: 0x26be, Stride: 1},
+		{Lo: 0x26c4, Hi: 0x26c5, Stride: 1},
+		{Lo: 0x26ce, Hi: 0x26d4, Stride: 6},
+		{Lo: 0x26ea, Hi: 0x26ea, Stride: 1},
+		{Lo: 0x26f2, Hi: 0x26f3, Stride: 1},
+		{Lo: 0x26f5, Hi: 0x26fa, Stride: 5},
+		{Lo: 0x26fd, Hi: 0x2705, Stride: 8},
+		{Lo: 0x270a, Hi: 0x270b, Stride: 1},
+		{Lo: 0x2728, Hi: 0x274c, Stride: 36},
+		{Lo: 0x274e, Hi: 0x274e, Stride: 1},
+		{Lo: 0x2753, Hi: 0x2755, Stride: 1},
+		{Lo: 0x2757, Hi: 0x2757, Stride: 1},
+		{Lo: 0x2795, Hi: 0x2797, Stride: 1},
+		{Lo: 0x27b0, Hi: 0x27bf, Stride: 15},
+		{Lo: 0x2b1b, Hi: 0x2b1c, Stride: 1},
+		{Lo: 0x2b50, Hi: 0x2b55, Stride: 5},
+		{Lo: 0x2e80, Hi: 0x303e, Stride: 1},
+		{Lo: 0x3041, Hi: 0x33ff, Stride: 1},
+		{Lo: 0x3400, Hi: 0x4dbf, Stride: 1},
+		{Lo: 0x4e00, Hi: 0x9fff, Stride: 1},
+		{Lo: 0xa000, Hi: 0xa4cf, Stride: 1},
+		{Lo: 0xa960, Hi: 0xa97f, Stride: 1},
+		{Lo: 0xac00, Hi: 0xd7a3, Stride: 1},
+		{Lo: 0xf900, Hi: 0xfaff, Stride: 1},
+		{Lo: 0xfe10, Hi: 0xfe19, Stride: 1},
+		{Lo: 0xfe30, Hi: 0xfe6f, Stride: 1},
+		{Lo: 0xff00, Hi: 0xff60, Stride: 1},
+		{Lo: 0xffe0, Hi: 0xffe6, Stride: 1},
+	},
+	R32: []unicode.Range32{
+		{Lo: 0x16fe0, Hi: 0x16fe4, Stride: 1},
+		{Lo: 0x17000, Hi: 0x18aff, Stride: 1},
+		{Lo: 0x1b000, Hi: 0x1b2ff, Stride: 1},
+		{Lo: 0x1f004, Hi: 0x1f0cf, Stride: 203},
+		{Lo: 0x1f18e, Hi: 0x1f18e, Stride: 1},
+		{Lo: 0x1f191, Hi: 0x1f19a, Stride: 1},
+		{Lo: 0x1f200, Hi: 0x1f251, Stride: 1},
+		{Lo: 0x1f260, Hi: 0x1f265, Stride: 1},
+		{Lo: 0x1f300, Hi: 0x1f64f, Stride: 1},
+		{Lo: 0x1f680, Hi: 0x1f6ff, Stride: 1},
+		{Lo: 0x1f7e0, Hi: 0x1f7eb, Stride: 1},
+		{Lo: 0x1f90c, Hi: 0x1f9ff, Stride: 1},
+		{Lo: 0x1fa70, Hi: 0x1faff, Stride: 1},
+		{Lo: 0x20000, Hi: 0x2fffd, Stride: 1},
+		{Lo: 0x30000, Hi: 0x3fffd, Stride: 1},
+	},
+}
+
+const (
+	zeroWidthJoiner = '\u200d'
+	// Range of regional indicator symbols, pairs of which form flags
+	regionalIndicatorFirst = '\U0001f1e6'
+	regionalIndicatorLast  = '\U0001f1ff'
+	// Range of emoji skin tone modifiers
+	emojiModifierFirst = '\U0001f3fb'
+	emojiModifierLast  = '\U0001f3ff'
+)
+
+// runeWidth returns the number of terminal cells the supplied rune
+// occupies on its own: zero for combining marks, format and control
+// characters, two for wide characters, and one for everything else.
+func runeWidth(r rune) int {
+	switch {
+	case r == 0,
+		unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf, unicode.Cc),
+		// Hangul medial vowels and final consonants join onto
+		// the preceding syllable
+		r >= 0x1160 && r <= 0x11ff:
+		return 0
+	case r >= regionalIndicatorFirst && r <= regionalIndicatorLast:
+		return 1
+	case unicode.Is(wide, r):
+		return 2
+	default:
+		return 1
+	}
+}
+
+// displayWidth returns the number of terminal cells the supplied
+// string occupies. As well as the widths of individual runes, it
+// accounts for emoji sequences rendered as a single glyph: those
+// joined with zero-width joiners, emoji with skin tone modifiers, and
+// flags made from pairs of regional indicators.
+func displayWidth(s string) int {
+	width := 0
+	joined := false
+	regional := false
+
+	for _, r := range s {
+		switch {
+		case r == zeroWidthJoiner:
+			// Whatever follows is drawn as part of the
+			// preceding glyph
+			joined = true
+			continue
+		case joined:
+			joined = false
+			continue
+		case r >= emojiModifierFirst && r <= emojiModifierLast:
+			continue
+		case r >= regionalIndicatorFirst && r <= regionalIndicatorLast:
+			// Each pair of regional indicators forms a
+			// two-cell flag
+			if !regional {
+				width += 2
+			}
+			regional = !regional
+			continue
+		}
+
+		regional = false
+		width += runeWidth(r)
+	}
+
+	return width
+}
@@ -0,0 +1,76 @@
+package gemtext
+
+import "testing"
+
+func TestDisplayWidth(t *testing.T) {
+	testCases := map[string]struct {
+		s        string
+		expected int
+	}{
+		"empty":                 {s: "", expected: 0},
+		"ascii":                 {s: "hello", expected: 5},
+		"accented precomposed":  {s: "café", expected: 4},
+		"accented combining":    {s: "cafe\u0301", expected: 4},
+		"cjk":                   {s: "日本語", expected: 6},
+		"hangul":                {s: "한국어", expected: 6},
+		"fullwidth":             {s: "ＡＢＣ", expected: 6},
+		"mixed":                 {s: "Go言語", expected: 6},
+		"emoji":                 {s: "🚀", expected: 2},
+		"emoji zwj sequence":    {s: "👩\u200d💻", expected: 2},
+		"emoji skin tone":       {s: "👍🏽", expected: 2},
+		"flag":                  {s: "🇬🇧", expected: 2},
+		"two flags":             {s: "🇬🇧🇯🇵", expected: 4},
+		"zero width space":      {s: "a\u200bb", expected: 2},
+		"box drawing is narrow": {s: "──", expected: 2},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if got := displayWidth(tc.s); got != tc.expected {
+				t.Errorf("got width of %d for '%s', want %d", got, tc.s, tc.expected)
+			}
+		})
+	}
+}
+
+func TestSegments(t *testing.T) {
+	testCases := map[string]struct {
+		line     string
+		expected []segment
+	}{
+		"words": {
+			line:     "one  two",
+			expected: []segment{{text: "one", space: true}, {text: "two", space: true}},
+		},
+		"ideographs": {
+			line:     "日本語",
+			expected: []segment{{text: "日", space: true}, {text: "本"}, {text: "語"}},
+		},
+		"closing punctuation stays with preceding character": {
+			line:     "です。",
+			expected: []segment{{text: "で", space: true}, {text: "す。"}},
+		},
+		"opening punctuation stays with following character": {
+			line:     "「本」",
+			expected: []segment{{text: "「本」", space: true}},
+		},
+		"latin within cjk": {
+			line:     "Go言語",
+			expected: []segment{{text: "Go", space: true}, {text: "言"}, {text: "語"}},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			got := segments(tc.line)
+			if len(got) != len(tc.expected) {
+				t.Fatalf("got %d segments (%v), want %d", len(got), got, len(tc.expected))
+			}
+			for i, seg := range got {
+				if seg != tc.expected[i] {
+					t.Errorf("segment %d does not match; got %v, want %v", i, seg, tc.expected[i])
+				}
+			}
+		})
+	}
+}
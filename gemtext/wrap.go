@@ -0,0 +1,336 @@
+package gemtext
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// Overflow is a strategy for handling words too long to fit on a
+// line on their own.
+type Overflow int
+
+const (
+	// OverflowNone leaves long words whole, overflowing the line
+	OverflowNone Overflow = iota
+	// OverflowBreak breaks long words at the line width
+	OverflowBreak
+	// OverflowURL breaks long words after URL and path separators
+	// where possible, and at the line width otherwise
+	OverflowURL
+	// OverflowEllipsis truncates long words, marking them with an
+	// ellipsis
+	OverflowEllipsis
+)
+
+var overflowNames = map[string]Overflow{
+	"none":     OverflowNone,
+	"break":    OverflowBreak,
+	"url":      OverflowURL,
+	"ellipsis": OverflowEllipsis,
+}
+
+// ParseOverflow returns the overflow strategy with the supplied name;
+// one of "none", "break", "url" or "ellipsis".
+func ParseOverflow(name string) (Overflow, error) {
+	overflow, ok := overflowNames[name]
+	if !ok {
+		return OverflowNone, fmt.Errorf("unknown overflow strategy '%s'", name)
+	}
+
+	return overflow, nil
+}
+
+// Characters after which URLs and paths may be broken
+const urlSeparators = "/?&=#.-_:~"
+
+// Hyphenation is a hyphenation dictionary, mapping lower-cased words
+// to the rune offsets at which they may be hyphenated.
+type Hyphenation map[string][]int
+
+// LoadHyphenation reads a hyphenation dictionary from the supplied
+// reader. Dictionaries list one word per line, with hyphens marking
+// the points at which it may be broken, e.g. "hy-phen-a-tion". Blank
+// lines and lines starting with '#' are ignored.
+func LoadHyphenation(r io.Reader) (Hyphenation, error) {
+	h := make(Hyphenation)
+
+	s := bufio.NewScanner(r)
+	i := 0
+	for s.Scan() {
+		i++
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.ContainsAny(line, " \t") {
+			return nil, fmt.Errorf("invalid hyphenation entry at line %d ('%s')", i, line)
+		}
+
+		var word strings.Builder
+		var points []int
+		offset := 0
+		for _, r := range strings.ToLower(line) {
+			if r == '-' {
+				if offset > 0 {
+					points = append(points, offset)
+				}
+				continue
+			}
+			word.WriteRune(r)
+			offset++
+		}
+		h[word.String()] = points
+	}
+
+	return h, s.Err()
+}
+
+// WordOverflow sets the strategy used for words too long to fit on
+// a line.
+func WordOverflow(overflow Overflow) Option {
+	return func(o *outputOptions) {
+		o.overflow = overflow
+	}
+}
+
+// Hyphenate sets a hyphenation dictionary, used to hyphenate words that
+// would otherwise not fit at the end of a line.
+func Hyphenate(h Hyphenation) Option {
+	return func(o *outputOptions) {
+		o.hyphenation = h
+	}
+}
+
+// Justify sets whether wrapped text is fully justified, by widening
+// the spaces between words so that each line but the last of a
+// paragraph fills the available width. Otherwise, text is left
+// aligned with a ragged right edge.
+func Justify(justify bool) Option {
+	return func(o *outputOptions) {
+		o.justify = justify
+	}
+}
+
+// wrapOptions holds settings for word-wrapping.
+type wrapOptions struct {
+	overflow    Overflow
+	hyphenation Hyphenation
+	justify     bool
+}
+
+// wrap takes the supplied line and wraps it with the default options.
+func wrap(width int, line string) []string {
+	return wrapOptions{}.wrap(width, line)
+}
+
+// wrap takes the supplied line and wraps it at line break opportunities
+// based on the supplied width, measured in terminal cells. It returns a
+// slice of wrapped lines.
+func (o wrapOptions) wrap(width int, line string) []string {
+	var wrapped []string
+
+	if len(line) == 0 {
+		wrapped = append(wrapped, line)
+		return wrapped
+	}
+
+	segs := segments(line)
+
+	// May just have spaces on a line
+	if len(segs) == 0 {
+		return wrapped
+	}
+
+	var wrappedLine string
+	spaceLeft := width
+	for len(segs) > 0 {
+		seg := segs[0]
+		segs = segs[1:]
+
+		sep := ""
+		if seg.space && wrappedLine != "" {
+			sep = " "
+		}
+
+		segWidth := displayWidth(seg.text)
+		if len(sep)+segWidth <= spaceLeft {
+			wrappedLine += sep + seg.text
+			spaceLeft -= len(sep) + segWidth
+			continue
+		}
+
+		// Doesn't fit; end the line with as much of the word as
+		// will hyphenate into the space left, and carry on with
+		// the rest of it
+		if head, tail, ok := o.hyphenate(seg.text, spaceLeft-len(sep)); ok {
+			wrapped = append(wrapped, wrappedLine+sep+head)
+			wrappedLine, spaceLeft = "", width
+			segs = append([]segment{{text: tail}}, segs...)
+			continue
+		}
+
+		if wrappedLine != "" {
+			wrapped = append(wrapped, wrappedLine)
+		}
+
+		// Still too long for a line of its own
+		if segWidth > width {
+			pieces := o.overflowWord(width, seg.text)
+			wrapped = append(wrapped, pieces[:len(pieces)-1]...)
+			seg.text = pieces[len(pieces)-1]
+			segWidth = displayWidth(seg.text)
+		}
+
+		wrappedLine = seg.text
+		spaceLeft = width - segWidth
+	}
+
+	wrapped = append(wrapped, wrappedLine)
+
+	if o.justify {
+		// The last line of a paragraph is left ragged
+		for i := range wrapped[:len(wrapped)-1] {
+			wrapped[i] = justify(width, wrapped[i])
+		}
+	}
+
+	return wrapped
+}
+
+// justify pads the spaces between the words of the supplied line so
+// that it fills width. Extra spaces are spread evenly, with any left
+// over going to the leftmost gaps. Lines without spaces are returned
+// unchanged.
+func justify(width int, line string) string {
+	words := strings.Split(line, " ")
+	gaps := len(words) - 1
+	extra := width - displayWidth(line)
+	if gaps == 0 || extra <= 0 {
+		return line
+	}
+
+	var b strings.Builder
+	for i, word := range words {
+		b.WriteString(word)
+		if i == gaps {
+			break
+		}
+		spaces := 1 + extra/gaps
+		if i < extra%gaps {
+			spaces++
+		}
+		b.WriteString(strings.Repeat(" ", spaces))
+	}
+
+	return b.String()
+}
+
+// hyphenate splits the supplied word at the latest hyphenation point
+// that leaves its first part, plus a hyphen, fitting into the supplied
+// width. Leading and trailing punctuation is ignored when looking the
+// word up in the dictionary.
+func (o wrapOptions) hyphenate(word string, width int) (head, tail string, ok bool) {
+	if o.hyphenation == nil {
+		return "", "", false
+	}
+
+	runes := []rune(word)
+	start, end := 0, len(runes)
+	for start < end && unicode.IsPunct(runes[start]) {
+		start++
+	}
+	for end > start && unicode.IsPunct(runes[end-1]) {
+		end--
+	}
+
+	points := o.hyphenation[strings.ToLower(string(runes[start:end]))]
+	for i := len(points) - 1; i >= 0; i-- {
+		head = string(runes[:start+points[i]]) + "-"
+		if displayWidth(head) <= width {
+			return head, string(runes[start+points[i]:]), true
+		}
+	}
+
+	return "", "", false
+}
+
+// overflowWord splits a word too long to fit on a line into pieces,
+// according to the overflow strategy. All but the last piece fill a
+// line of their own.
+func (o wrapOptions) overflowWord(width int, word string) []string {
+	switch o.overflow {
+	case OverflowBreak:
+		return breakWord(width, word)
+	case OverflowURL:
+		return breakURL(width, word)
+	case OverflowEllipsis:
+		return []string{truncate(width, word)}
+	default:
+		return []string{word}
+	}
+}
+
+// breakWord breaks the supplied word into pieces no wider than width.
+// Zero-width characters are kept with the character they follow.
+func breakWord(width int, word string) []string {
+	var pieces []string
+
+	start := 0
+	for i, r := range word {
+		if i > start && runeWidth(r) > 0 && displayWidth(word[start:i])+runeWidth(r) > width {
+			pieces = append(pieces, word[start:i])
+			start = i
+		}
+	}
+
+	return append(pieces, word[start:])
+}
+
+// breakURL breaks the supplied URL or path into pieces no wider than
+// width, preferring to break after separator characters.
+func breakURL(width int, url string) []string {
+	// Split into parts, each ending with a separator
+	var parts []string
+	start := 0
+	for i, r := range url {
+		if strings.ContainsRune(urlSeparators, r) {
+			parts = append(parts, url[start:i+1])
+			start = i + 1
+		}
+	}
+	if start < len(url) {
+		parts = append(parts, url[start:])
+	}
+
+	var pieces []string
+	piece := ""
+	for _, part := range parts {
+		if displayWidth(piece+part) <= width {
+			piece += part
+			continue
+		}
+		if piece != "" {
+			pieces = append(pieces, piece)
+		}
+
+		// Parts too long on their own are broken anywhere
+		broken := breakWord(width, part)
+		pieces = append(pieces, broken[:len(broken)-1]...)
+		piece = broken[len(broken)-1]
+	}
+
+	return append(pieces, piece)
+}
+
+// truncate shortens the supplied word to fit within width, ending it
+// with an ellipsis.
+func truncate(width int, word string) string {
+	if width < 2 {
+		return "…"
+	}
+
+	return breakWord(width-1, word)[0] + "…"
+}
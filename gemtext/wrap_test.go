@@ -0,0 +1,192 @@
+package gemtext
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWrapOverflow(t *testing.T) {
+	url := "gemini://some.capsule/a/long/path/to.gmi"
+	testCases := map[string]struct {
+		overflow     Overflow
+		line         string
+		wrappedLines []string
+	}{
+		"none": {
+			overflow:     OverflowNone,
+			line:         "see " + url + " here",
+			wrappedLines: []string{"see", url, "here"},
+		},
+		"break": {
+			overflow:     OverflowBreak,
+			line:         "see " + url + " here",
+			wrappedLines: []string{"see", "gemini://some.capsul", "e/a/long/path/to.gmi", "here"},
+		},
+		"url": {
+			overflow:     OverflowURL,
+			line:         "see " + url + " here",
+			wrappedLines: []string{"see", "gemini://some.", "capsule/a/long/path/", "to.gmi here"},
+		},
+		"url with long part": {
+			overflow:     OverflowURL,
+			line:         "/averyveryverylongdirectoryname/file",
+			wrappedLines: []string{"/", "averyveryverylongdir", "ectoryname/file"},
+		},
+		"ellipsis": {
+			overflow:     OverflowEllipsis,
+			line:         "see " + url + " here",
+			wrappedLines: []string{"see", "gemini://some.capsu…", "here"},
+		},
+		"wide characters": {
+			overflow:     OverflowBreak,
+			line:         "ｆｕｌｌｗｉｄｔｈｆｕｌｌｗｉｄｔｈ",
+			wrappedLines: []string{"ｆｕｌｌｗｉｄｔｈｆ", "ｕｌｌｗｉｄｔｈ"},
+		},
+		"combining marks stay together": {
+			overflow:     OverflowBreak,
+			line:         strings.Repeat("e\u0301", 21),
+			wrappedLines: []string{strings.Repeat("e\u0301", 20), "e\u0301"},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			got := wrapOptions{overflow: tc.overflow}.wrap(20, tc.line)
+			if len(got) != len(tc.wrappedLines) {
+				t.Fatalf("got %d wrapped lines (%q), want %d", len(got), got, len(tc.wrappedLines))
+			}
+			for i, line := range got {
+				if line != tc.wrappedLines[i] {
+					t.Errorf("line %d does not match; got '%s', want '%s'", i, line, tc.wrappedLines[i])
+				}
+			}
+		})
+	}
+}
+
+func TestWrapHyphenation(t *testing.T) {
+	dict := `# Test dictionary
+hy-phen-a-tion
+dic-tion-ar-y
+`
+	h, err := LoadHyphenation(strings.NewReader(dict))
+	if err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+
+	testCases := map[string]struct {
+		line         string
+		wrappedLines []string
+	}{
+		"hyphenated": {
+			line:         "uses some hyphenation here",
+			wrappedLines: []string{"uses some hyphen-", "ation here"},
+		},
+		"punctuation and case": {
+			line:         "a good (Dictionary).",
+			wrappedLines: []string{"a good (Diction-", "ary)."},
+		},
+		"not in dictionary": {
+			line:         "uses some punctuation",
+			wrappedLines: []string{"uses some", "punctuation"},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			got := wrapOptions{hyphenation: h}.wrap(17, tc.line)
+			if len(got) != len(tc.wrappedLines) {
+				t.Fatalf("got %d wrapped lines (%q), want %d", len(got), got, len(tc.wrappedLines))
+			}
+			for i, line := range got {
+				if line != tc.wrappedLines[i] {
+					t.Errorf("line %d does not match; got '%s', want '%s'", i, line, tc.wrappedLines[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLoadHyphenation(t *testing.T) {
+	h, err := LoadHyphenation(strings.NewReader("Hy-phen-a-tion\n\n-lead-ing\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+
+	expected := map[string][]int{
+		"hyphenation": {2, 6, 7},
+		"leading":     {4},
+	}
+	for word, want := range expected {
+		got, ok := h[word]
+		if !ok {
+			t.Errorf("expected word '%s' not found", word)
+			continue
+		}
+		if len(got) != len(want) {
+			t.Errorf("got hyphenation points %v for '%s', want %v", got, word, want)
+			continue
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Errorf("got hyphenation points %v for '%s', want %v", got, word, want)
+				break
+			}
+		}
+	}
+
+	_, err = LoadHyphenation(strings.NewReader("two words\n"))
+	if err == nil || !strings.Contains(err.Error(), "invalid hyphenation entry at line 1") {
+		t.Errorf("got error '%v', want invalid entry error", err)
+	}
+}
+
+func TestParseOverflow(t *testing.T) {
+	for name, want := range overflowNames {
+		got, err := ParseOverflow(name)
+		if err != nil {
+			t.Errorf("unexpected error: %q", err)
+		}
+		if got != want {
+			t.Errorf("got overflow %d for '%s', want %d", got, name, want)
+		}
+	}
+
+	if _, err := ParseOverflow("invalid"); err == nil {
+		t.Error("expected an error, but got nil")
+	}
+}
+
+func TestWrapJustify(t *testing.T) {
+	testCases := map[string]struct {
+		line         string
+		wrappedLines []string
+	}{
+		"justified": {
+			line:         "one two three four five six seven eight",
+			wrappedLines: []string{"one  two  three", "four  five  six", "seven eight"},
+		},
+		"uneven spacing": {
+			line:         "a bb ccc dddd eeeeeeeeee",
+			wrappedLines: []string{"a  bb  ccc dddd", "eeeeeeeeee"},
+		},
+		"single word lines": {
+			line:         "abcdefghijklmnop qrstuvwxyz",
+			wrappedLines: []string{"abcdefghijklmnop", "qrstuvwxyz"},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			got := wrapOptions{justify: true}.wrap(15, tc.line)
+			if len(got) != len(tc.wrappedLines) {
+				t.Fatalf("got %d wrapped lines (%q), want %d", len(got), got, len(tc.wrappedLines))
+			}
+			for i, line := range got {
+				if line != tc.wrappedLines[i] {
+					t.Errorf("line %d does not match; got '%s', want '%s'", i, line, tc.wrappedLines[i])
+				}
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ module github.com/chriswalker/gmi-utils
 go 1.16
 
 require (
+	golang.org/x/net v0.0.0-20211020060615-d418f374d309
 	golang.org/x/sys v0.0.0-20211019181941-9d821ace8654 // indirect
 	golang.org/x/term v0.0.0-20210927222741-03fcf44c2211
 )
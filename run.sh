@@ -9,21 +9,39 @@ build() {
   build-gmiget
   build-gmifmt
   build-gmilinks
+  build-gmimon
+  build-gmibench
+  build-gmiconv
 }
 
 build-gmiget() {
   echo "Building gmiget..."
-  go build -o bin/gmiget cmd/gmiget/main.go
+  go build -o bin/gmiget ./cmd/gmiget
 }
 
 build-gmifmt() {
   echo "Building gmifmt..."
-  go build -o bin/gmifmt cmd/gmifmt/main.go
+  go build -o bin/gmifmt ./cmd/gmifmt
 }
 
 build-gmilinks() {
   echo "Building gmilinks..."
-  go build -o bin/gmilinks cmd/gmilinks/main.go
+  go build -o bin/gmilinks ./cmd/gmilinks
+}
+
+build-gmimon() {
+  echo "Building gmimon..."
+  go build -o bin/gmimon ./cmd/gmimon
+}
+
+build-gmibench() {
+  echo "Building gmibench..."
+  go build -o bin/gmibench ./cmd/gmibench
+}
+
+build-gmiconv() {
+  echo "Building gmiconv..."
+  go build -o bin/gmiconv ./cmd/gmiconv
 }
 
 test() {
@@ -44,7 +62,7 @@ die() {
 
 action="$1"
 case $action in
-  lint | build | build-gmiget | build-gmifmt | build-gmilinks | build-gmisrv | test | help)
+  lint | build | build-gmiget | build-gmifmt | build-gmilinks | build-gmimon | build-gmibench | build-gmiconv | build-gmisrv | test | help)
     "$@"
     ;;
   *)
@@ -0,0 +1,172 @@
+package terminal
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// ColourDepth is the range of colours a terminal can display.
+type ColourDepth int
+
+const (
+	// NoColour terminals display text without colour
+	NoColour ColourDepth = iota
+	// Colour16 terminals display the 16 ANSI colours
+	Colour16
+	// Colour256 terminals display the xterm 256 colour palette
+	Colour256
+	// TrueColour terminals display any 24-bit RGB colour
+	TrueColour
+)
+
+// Magic numbers of the legacy and extended terminfo formats, the latter
+// having 32-bit numeric capabilities.
+const (
+	terminfoMagic   = 0432
+	terminfoMagic32 = 01036
+	// Index of the max_colors numeric capability
+	terminfoColours = 13
+)
+
+// GetColourDepth returns the colour depth to use for output to out: no
+// colour if it isn't a terminal or the NO_COLOR environment variable is
+// set, and otherwise that detected by DetectColourDepth.
+func GetColourDepth(out *os.File) ColourDepth {
+	if os.Getenv("NO_COLOR") != "" || !term.IsTerminal(int(out.Fd())) {
+		return NoColour
+	}
+
+	return DetectColourDepth()
+}
+
+// DetectColourDepth returns the colour depth of the current terminal,
+// as advertised by the COLORTERM and TERM environment variables, or
+// failing that by the terminal's terminfo entry.
+func DetectColourDepth() ColourDepth {
+	return detectColourDepth(os.Getenv, terminfoDirs())
+}
+
+// detectColourDepth returns the colour depth advertised by the
+// environment, looking up terminfo entries in the supplied directories.
+func detectColourDepth(getenv func(string) string, dirs []string) ColourDepth {
+	switch strings.ToLower(getenv("COLORTERM")) {
+	case "truecolor", "24bit":
+		return TrueColour
+	}
+
+	name := getenv("TERM")
+	switch {
+	case name == "" || name == "dumb":
+		return NoColour
+	case strings.HasSuffix(name, "-truecolor"), strings.HasSuffix(name, "-direct"):
+		return TrueColour
+	case strings.HasSuffix(name, "-256color"):
+		return Colour256
+	}
+
+	colours, err := terminfoColourCount(name, dirs)
+	switch {
+	case err != nil:
+		// Most terminals manage the basic colours
+		return Colour16
+	case colours >= 1<<24:
+		return TrueColour
+	case colours >= 256:
+		return Colour256
+	case colours >= 8:
+		return Colour16
+	default:
+		return NoColour
+	}
+}
+
+// terminfoDirs returns the directories searched for terminfo entries,
+// in order.
+func terminfoDirs() []string {
+	var dirs []string
+	if dir := os.Getenv("TERMINFO"); dir != "" {
+		dirs = append(dirs, dir)
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".terminfo"))
+	}
+	if val := os.Getenv("TERMINFO_DIRS"); val != "" {
+		for _, dir := range strings.Split(val, ":") {
+			// An empty entry stands for the default location
+			if dir == "" {
+				dir = "/usr/share/terminfo"
+			}
+			dirs = append(dirs, dir)
+		}
+	}
+
+	return append(dirs, "/etc/terminfo", "/lib/terminfo", "/usr/share/terminfo")
+}
+
+// terminfoColourCount returns the number of colours the named terminal
+// supports, according to the first terminfo entry found for it in the
+// supplied directories. The count is -1 if the entry doesn't say.
+func terminfoColourCount(name string, dirs []string) (int, error) {
+	if strings.ContainsAny(name, "/\\") || strings.HasPrefix(name, ".") {
+		return 0, errors.New("invalid terminal name")
+	}
+
+	for _, dir := range dirs {
+		// Entries are filed under their first letter, or on some
+		// systems its hex code
+		for _, sub := range []string{name[:1], fmt.Sprintf("%02x", name[0])} {
+			data, err := os.ReadFile(filepath.Join(dir, sub, name))
+			if err != nil {
+				continue
+			}
+			return parseTerminfoColours(data)
+		}
+	}
+
+	return 0, os.ErrNotExist
+}
+
+// parseTerminfoColours returns the max_colors capability of the
+// supplied compiled terminfo entry, or -1 if it isn't set.
+func parseTerminfoColours(data []byte) (int, error) {
+	if len(data) < 12 {
+		return 0, errors.New("terminfo entry too short")
+	}
+
+	header := make([]int, 6)
+	for i := range header {
+		header[i] = int(binary.LittleEndian.Uint16(data[i*2:]))
+	}
+	size := 2
+	switch header[0] {
+	case terminfoMagic:
+	case terminfoMagic32:
+		size = 4
+	default:
+		return 0, errors.New("unknown terminfo format")
+	}
+
+	namesSize, boolCount, numCount := header[1], header[2], header[3]
+	if numCount <= terminfoColours {
+		return -1, nil
+	}
+
+	// Numbers are aligned to an even offset
+	offset := 12 + namesSize + boolCount
+	offset += offset % 2
+	offset += terminfoColours * size
+	if len(data) < offset+size {
+		return 0, errors.New("terminfo entry too short")
+	}
+
+	if size == 4 {
+		return int(int32(binary.LittleEndian.Uint32(data[offset:]))), nil
+	}
+	return int(int16(binary.LittleEndian.Uint16(data[offset:]))), nil
+}
@@ -0,0 +1,125 @@
+package terminal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// terminfoEntry returns a compiled terminfo entry with the supplied
+// max_colors capability, in the legacy or extended format.
+func terminfoEntry(colours int, extended bool) []byte {
+	names := "test|test terminal\x00"
+	size, magic := 2, terminfoMagic
+	if extended {
+		size, magic = 4, terminfoMagic32
+	}
+
+	var b bytes.Buffer
+	for _, v := range []int{magic, len(names), 1, terminfoColours + 1, 0, 0} {
+		binary.Write(&b, binary.LittleEndian, uint16(v))
+	}
+	b.WriteString(names)
+	// A single boolean, then padding to an even offset
+	b.WriteByte(1)
+	if b.Len()%2 != 0 {
+		b.WriteByte(0)
+	}
+	for i := 0; i <= terminfoColours; i++ {
+		v := -1
+		if i == terminfoColours {
+			v = colours
+		}
+		if size == 4 {
+			binary.Write(&b, binary.LittleEndian, int32(v))
+		} else {
+			binary.Write(&b, binary.LittleEndian, int16(v))
+		}
+	}
+
+	return b.Bytes()
+}
+
+func TestDetectColourDepth(t *testing.T) {
+	dir := t.TempDir()
+	entries := map[string][]byte{
+		"t/test-8":      terminfoEntry(8, false),
+		"t/test-88":     terminfoEntry(88, false),
+		"74/test-256":   terminfoEntry(256, false),
+		"t/test-direct": terminfoEntry(1<<24, true),
+		"t/test-mono":   terminfoEntry(-1, false),
+	}
+	for path, data := range entries {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testCases := map[string]struct {
+		env      map[string]string
+		expected ColourDepth
+	}{
+		"colorterm truecolor": {
+			env:      map[string]string{"COLORTERM": "truecolor", "TERM": "xterm"},
+			expected: TrueColour,
+		},
+		"colorterm 24bit": {
+			env:      map[string]string{"COLORTERM": "24bit"},
+			expected: TrueColour,
+		},
+		"no term": {
+			expected: NoColour,
+		},
+		"dumb term": {
+			env:      map[string]string{"TERM": "dumb"},
+			expected: NoColour,
+		},
+		"256color term": {
+			env:      map[string]string{"TERM": "screen-256color"},
+			expected: Colour256,
+		},
+		"direct term": {
+			env:      map[string]string{"TERM": "xterm-direct"},
+			expected: TrueColour,
+		},
+		"terminfo 8 colours": {
+			env:      map[string]string{"TERM": "test-8"},
+			expected: Colour16,
+		},
+		"terminfo 88 colours": {
+			env:      map[string]string{"TERM": "test-88"},
+			expected: Colour16,
+		},
+		"terminfo 256 colours in hex directory": {
+			env:      map[string]string{"TERM": "test-256"},
+			expected: Colour256,
+		},
+		"terminfo direct colour": {
+			env:      map[string]string{"TERM": "test-direct"},
+			expected: TrueColour,
+		},
+		"terminfo no colour": {
+			env:      map[string]string{"TERM": "test-mono"},
+			expected: NoColour,
+		},
+		"unknown term": {
+			env:      map[string]string{"TERM": "unknown"},
+			expected: Colour16,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			getenv := func(key string) string { return tc.env[key] }
+			if got := detectColourDepth(getenv, []string{dir}); got != tc.expected {
+				t.Errorf("got colour depth %d, want %d", got, tc.expected)
+			}
+		})
+	}
+}
@@ -2,17 +2,33 @@ package terminal
 
 import (
 	"os"
+	"strconv"
 	"syscall"
 
 	"golang.org/x/term"
 )
 
-const consoleDev = "/dev/tty"
+const (
+	consoleDev = "/dev/tty"
+	// Width assumed when it can't be determined
+	defaultWidth = 80
+)
 
+// GetWidth returns the width of the terminal in columns. If there is
+// no terminal to ask (e.g. when run from cron), it falls back to the
+// COLUMNS environment variable, and then to 80 columns.
 func GetWidth() int {
 	ttyFile := getTTY()
-	width, _, _ := term.GetSize(int(ttyFile.Fd())) // TODO err
-	return width
+	width, _, err := term.GetSize(int(ttyFile.Fd()))
+	if err == nil && width > 0 {
+		return width
+	}
+
+	if width, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && width > 0 {
+		return width
+	}
+
+	return defaultWidth
 }
 
 // Below functions taken from fzf, which has a great way of determining